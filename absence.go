@@ -0,0 +1,200 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ComputeAbsenceProofFromDB returns a proof that key has no value in the trie with the given root.
+// The proof has a nil Value, and its steps go as far towards key as the trie does.
+func ComputeAbsenceProofFromDB(db NodeReader, root common.Hash, key []byte) (*Proof, error) {
+	load := func(hash hashNode) (Step, error) { return readStep(db, hash) }
+	proof, path, err := proveFromDB(load, root, key)
+	if err != nil {
+		return nil, err
+	}
+	if proof != nil {
+		return nil, fmt.Errorf("key %X has a value", key)
+	}
+	return buildAbsenceProof(key, path), nil
+}
+
+// VerifyAbsence makes sure proof shows that its key has no value under rootHash
+func VerifyAbsence(proof *Proof, rootHash common.Hash) error {
+	if proof.Value != nil {
+		return fmt.Errorf("absence proof must not have a value")
+	}
+	value, err := walkProof(proof.Steps, rootHash, proof.Key)
+	if err != nil {
+		return err
+	}
+	if value != nil {
+		return fmt.Errorf("key %X has a value", proof.Key)
+	}
+	return nil
+}
+
+// computeProofOrAbsence returns a proof of the value of key under root, or an
+// absence proof (with a nil Value) if it has none
+func computeProofOrAbsence(db NodeReader, root common.Hash, key []byte) (*Proof, error) {
+	load := func(hash hashNode) (Step, error) { return readStep(db, hash) }
+	return proveOrAbsence(load, root, key)
+}
+
+// proveOrAbsence is computeProofOrAbsence with nodes taken from load
+func proveOrAbsence(load func(hashNode) (Step, error), root common.Hash, key []byte) (*Proof, error) {
+	proof, path, err := proveFromDB(load, root, key)
+	if err != nil {
+		return nil, err
+	}
+	if proof == nil {
+		proof = buildAbsenceProof(key, path)
+	}
+	return proof, nil
+}
+
+// verifyProofOrAbsence checks proof with VerifyAbsence if it has no value, or VerifyProof
+// otherwise, and returns the proven value
+func verifyProofOrAbsence(proof *Proof, rootHash common.Hash) ([]byte, error) {
+	if proof.Value == nil {
+		return nil, VerifyAbsence(proof, rootHash)
+	}
+	if err := VerifyProof(proof, rootHash); err != nil {
+		return nil, err
+	}
+	return proof.Value, nil
+}
+
+// buildAbsenceProof annotates the path towards a missing key like buildProof,
+// up to the node where key leaves the trie
+func buildAbsenceProof(key []byte, path []Step) *Proof {
+	hexkey := keybytesToHex(key)
+	for i, p := range path {
+		switch t := p.Step.(type) {
+		case *shortNode:
+			// on a mismatch, this is where key leaves the trie (and the last step)
+			if len(hexkey) >= len(t.Key) && bytes.Equal(t.Key, hexkey[:len(t.Key)]) {
+				hexkey = hexkey[len(t.Key):]
+			}
+		case *fullNode:
+			path[i].Index = int(hexkey[0])
+			hexkey = hexkey[1:]
+		}
+	}
+
+	return &Proof{
+		Steps:        path,
+		Key:          key,
+		HexRemainder: hexkey,
+	}
+}
+
+// walkProof follows key from rootHash through the raw nodes of steps, including any nodes
+// embedded in them, and returns the value stored under key, or nil if the steps show there is none.
+// Only Step.Raw is used, Step.Step, Step.Hash and Step.Index are ignored.
+func walkProof(steps []Step, rootHash common.Hash, key []byte) ([]byte, error) {
+	w, err := walkPath(steps, rootHash, key)
+	if err != nil {
+		return nil, err
+	}
+	return w.value, nil
+}
+
+// walk is what walkPath derives from the raw nodes and the key
+type walk struct {
+	// value is nil if key has no value
+	value []byte
+	// steps have Step, Hash and Index set as buildProof would
+	steps []Step
+	// remainder is what is left of the hex key after the last step, before any embedded nodes
+	remainder []byte
+}
+
+// walkPath is walkProof, also returning the annotations it derived for every step
+func walkPath(steps []Step, rootHash common.Hash, key []byte) (*walk, error) {
+	if len(steps) == 0 {
+		if rootHash == emptyRoot {
+			return &walk{remainder: keybytesToHex(key)}, nil
+		}
+		return nil, fmt.Errorf("proof has no steps")
+	}
+
+	w := &walk{steps: make([]Step, len(steps))}
+	hexkey := keybytesToHex(key)
+	expected := rootHash[:]
+	for i, step := range steps {
+		last := i == len(steps)-1
+		decoded, err := checkStep(i, step, expected)
+		if err != nil {
+			return nil, err
+		}
+		w.steps[i] = Step{Step: decoded, Hash: makeHashNode(step.Raw), Raw: step.Raw}
+
+		var cur node = decoded
+	descend:
+		for top := true; ; top = false {
+			switch n := cur.(type) {
+			case *shortNode:
+				if len(hexkey) < len(n.Key) || !bytes.Equal(n.Key, hexkey[:len(n.Key)]) {
+					// key leaves the trie here
+					cur = nil
+				} else {
+					hexkey = hexkey[len(n.Key):]
+					cur = n.Val
+				}
+			case *fullNode:
+				if len(hexkey) == 0 {
+					return nil, fmt.Errorf("step %d: key ends inside a full node", i)
+				}
+				if top {
+					w.steps[i].Index = int(hexkey[0])
+				}
+				cur = n.Children[hexkey[0]]
+				hexkey = hexkey[1:]
+			case hashNode:
+				if last {
+					return nil, fmt.Errorf("proof ends before reaching key %X", key)
+				}
+				expected = n
+				break descend
+			case valueNode:
+				if !last {
+					return nil, fmt.Errorf("proof has %d steps after the value", len(steps)-1-i)
+				}
+				w.value = n
+				return w, nil
+			case nil:
+				if !last {
+					return nil, fmt.Errorf("proof has %d steps after the key left the trie", len(steps)-1-i)
+				}
+				return w, nil
+			default:
+				return nil, fmt.Errorf("Unknown type: %T", cur)
+			}
+			if top && last {
+				w.remainder = hexkey
+			}
+		}
+	}
+	// every hashNode on a non-last step continues the loop, so this can't be reached
+	return nil, fmt.Errorf("proof ends before reaching key %X", key)
+}
+
+// checkStep makes sure the raw encoding of the i-th step hashes to expected,
+// and returns the node it encodes
+func checkStep(i int, step Step, expected []byte) (PathStep, error) {
+	if len(step.Raw) == 0 {
+		return nil, fmt.Errorf("step %d is missing the raw node encoding", i)
+	}
+	got := makeHashNode(step.Raw)
+	if !bytes.Equal(expected, got) {
+		return nil, fmt.Errorf("step %d has different calculated hash: %X\n  it should be %X", i, got, expected)
+	}
+	decoded, err := decodeNode(got, step.Raw, 0)
+	if err != nil {
+		return nil, fmt.Errorf("step %d cannot decode raw node: %v", i, err)
+	}
+	return decoded, nil
+}
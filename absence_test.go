@@ -0,0 +1,83 @@
+package proof
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAbsenceProof(t *testing.T) {
+	diskdb, tr, vals := diskTrie(t, 1000)
+	root := tr.Hash()
+
+	for i := 0; i < 20; i++ {
+		key := randBytes(32)
+		proof, err := ComputeAbsenceProofFromDB(diskdb, root, key)
+		if err != nil {
+			t.Fatalf("ComputeAbsenceProofFromDB: %+v", err)
+		}
+		if err := VerifyAbsence(proof, root); err != nil {
+			t.Fatalf("Invalid absence proof %+v", err)
+		}
+
+		// same steps can't show absence of a key that is there
+		proof.Key = vals[i].k
+		if err := VerifyAbsence(proof, root); err == nil {
+			t.Fatalf("Expected error for absence of existing key")
+		}
+	}
+
+	// nor can we build one for it
+	if _, err := ComputeAbsenceProofFromDB(diskdb, root, vals[0].k); err == nil {
+		t.Fatalf("Expected error for existing key")
+	}
+
+	// dropping the last step leaves the path unresolved
+	proof, err := ComputeAbsenceProofFromDB(diskdb, root, randBytes(32))
+	if err != nil {
+		t.Fatalf("ComputeAbsenceProofFromDB: %+v", err)
+	}
+	if len(proof.Steps) > 1 {
+		proof.Steps = proof.Steps[:len(proof.Steps)-1]
+		if err := VerifyAbsence(proof, root); err == nil {
+			t.Fatalf("Expected error for truncated proof")
+		}
+	}
+
+	// everything is absent from the empty trie
+	empty, err := ComputeAbsenceProofFromDB(NodeMap{}, emptyRoot, randBytes(32))
+	if err != nil {
+		t.Fatalf("ComputeAbsenceProofFromDB on empty trie: %+v", err)
+	}
+	if err := VerifyAbsence(empty, emptyRoot); err != nil {
+		t.Fatalf("Invalid absence proof on empty trie %+v", err)
+	}
+	if err := VerifyAbsence(empty, common.Hash{1}); err == nil {
+		t.Fatalf("Expected error for empty proof on non-empty root")
+	}
+}
+
+func TestAbsenceProofShortNode(t *testing.T) {
+	// the key shares a prefix with the only item, then diverges inside the short node
+	tr, root := stringTrie(t, []string{"fooled"})
+	member, err := ComputeProof(tr, []byte("fooled"))
+	if err != nil {
+		t.Fatalf("ComputeProof: %+v", err)
+	}
+	nodes := NodeMap{}
+	for _, step := range member.Steps {
+		nodes[common.BytesToHash(step.Hash)] = step.Raw
+	}
+
+	proof, err := ComputeAbsenceProofFromDB(nodes, root, []byte("foo"))
+	if err != nil {
+		t.Fatalf("ComputeAbsenceProofFromDB: %+v", err)
+	}
+	if err := VerifyAbsence(proof, root); err != nil {
+		t.Fatalf("Invalid absence proof %+v", err)
+	}
+	proof.Key = []byte("fooled")
+	if err := VerifyAbsence(proof, root); err == nil {
+		t.Fatalf("Expected error for absence of existing key")
+	}
+}
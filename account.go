@@ -0,0 +1,91 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// emptyCodeHash is the code hash of accounts without code
+var emptyCodeHash = common.BytesToHash(makeHashNode(nil))
+
+// Account is the state of an Ethereum account, as stored in the state trie
+type Account struct {
+	Nonce       uint64
+	Balance     *big.Int
+	StorageRoot common.Hash
+	CodeHash    common.Hash
+}
+
+// EmptyAccount returns the state of an account that is not in the state trie
+func EmptyAccount() *Account {
+	return &Account{
+		Balance:     new(big.Int),
+		StorageRoot: emptyRoot,
+		CodeHash:    emptyCodeHash,
+	}
+}
+
+// DecodeAccount parses the value of an account in the state trie,
+// rejecting anything but its canonical encoding
+func DecodeAccount(value []byte) (*Account, error) {
+	var account Account
+	if err := rlp.DecodeBytes(value, &account); err != nil {
+		return nil, fmt.Errorf("invalid account encoding: %v", err)
+	}
+	// the decoder already rejects most non-canonical input, re-encoding catches the rest
+	canonical, err := rlp.EncodeToBytes(&account)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(canonical, value) {
+		return nil, fmt.Errorf("non-canonical account encoding")
+	}
+	return &account, nil
+}
+
+// AccountProof is a proof of the state of one account
+type AccountProof struct {
+	Address common.Address
+	// Exists is false if the account is not in the state trie. Account is then
+	// EmptyAccount() and Proof is an absence proof, with a nil Value.
+	Exists  bool
+	Account *Account
+	// Proof is keyed by the hash of Address, as the state trie is a secure trie
+	Proof *Proof
+}
+
+// ComputeAccountProof proves the state of addr in the state trie with the given root
+func ComputeAccountProof(db NodeReader, stateRoot common.Hash, addr common.Address) (*AccountProof, error) {
+	proof, err := computeProofOrAbsence(db, stateRoot, makeHashNode(addr[:]))
+	if err != nil {
+		return nil, err
+	}
+	return newAccountProof(addr, proof)
+}
+
+// VerifyAccount checks that proof shows the state of addr under stateRoot, and returns
+// that state. proof may be an absence proof, for an account that doesn't exist.
+func VerifyAccount(proof *Proof, stateRoot common.Hash, addr common.Address) (*AccountProof, error) {
+	if !bytes.Equal(proof.Key, makeHashNode(addr[:])) {
+		return nil, fmt.Errorf("proof is not for account %X", addr)
+	}
+	if _, err := verifyProofOrAbsence(proof, stateRoot); err != nil {
+		return nil, err
+	}
+	return newAccountProof(addr, proof)
+}
+
+func newAccountProof(addr common.Address, proof *Proof) (*AccountProof, error) {
+	if proof.Value == nil {
+		return &AccountProof{Address: addr, Exists: false, Account: EmptyAccount(), Proof: proof}, nil
+	}
+	account, err := DecodeAccount(proof.Value)
+	if err != nil {
+		return nil, fmt.Errorf("account %X: %v", addr, err)
+	}
+	return &AccountProof{Address: addr, Exists: true, Account: account, Proof: proof}, nil
+}
@@ -0,0 +1,128 @@
+package proof
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestAccountProof(t *testing.T) {
+	addr := common.HexToAddress("0xc0ffee")
+	missing := common.HexToAddress("0xdead")
+	code := []byte{0x60, 0x00, 0x60, 0x00}
+
+	diskdb, statedb, root := testState(t, func(statedb *state.StateDB) {
+		statedb.SetNonce(addr, 7)
+		statedb.SetBalance(addr, big.NewInt(123456789))
+		statedb.SetCode(addr, code)
+		statedb.SetState(addr, common.Hash{1}, common.Hash{2})
+	})
+
+	computed, err := ComputeAccountProof(diskdb, root, addr)
+	if err != nil {
+		t.Fatalf("ComputeAccountProof: %+v", err)
+	}
+	verified, err := VerifyAccount(computed.Proof, root, addr)
+	if err != nil {
+		t.Fatalf("VerifyAccount: %+v", err)
+	}
+	account := verified.Account
+	if !verified.Exists || account.Nonce != 7 || account.Balance.Int64() != 123456789 {
+		t.Fatalf("Unexpected account %+v", account)
+	}
+	if account.StorageRoot != statedb.StorageTrie(addr).Hash() {
+		t.Fatalf("Unexpected storage root %X", account.StorageRoot)
+	}
+	if account.CodeHash != common.BytesToHash(makeHashNode(code)) {
+		t.Fatalf("Unexpected code hash %X", account.CodeHash)
+	}
+	if _, err := VerifyAccount(computed.Proof, root, missing); err == nil {
+		t.Fatalf("Expected error for proof of another address")
+	}
+
+	// missing accounts are proven empty
+	computed, err = ComputeAccountProof(diskdb, root, missing)
+	if err != nil {
+		t.Fatalf("ComputeAccountProof: %+v", err)
+	}
+	verified, err = VerifyAccount(computed.Proof, root, missing)
+	if err != nil {
+		t.Fatalf("VerifyAccount: %+v", err)
+	}
+	empty := verified.Account
+	if verified.Exists || empty.Nonce != 0 || empty.Balance.Sign() != 0 ||
+		empty.StorageRoot != emptyRoot || empty.CodeHash != emptyCodeHash {
+		t.Fatalf("Unexpected missing account %+v", empty)
+	}
+}
+
+func TestDecodeAccount(t *testing.T) {
+	valid, err := rlp.EncodeToBytes(EmptyAccount())
+	if err != nil {
+		t.Fatalf("Encoding: %+v", err)
+	}
+	if _, err := DecodeAccount(valid); err != nil {
+		t.Fatalf("DecodeAccount: %+v", err)
+	}
+
+	cases := map[string][]byte{
+		"empty":          nil,
+		"trailing bytes": append(append([]byte{}, valid...), 0x80),
+		"too few fields": mustEncode(t, []interface{}{uint64(1), big.NewInt(1), emptyRoot}),
+		"too many fields": mustEncode(t, []interface{}{
+			uint64(1), big.NewInt(1), emptyRoot, emptyCodeHash, uint64(1),
+		}),
+		"short hash": mustEncode(t, []interface{}{uint64(1), big.NewInt(1), emptyRoot, emptyCodeHash[:31]}),
+		// nonce 1 as a two byte string with a leading zero
+		"non-canonical nonce": mustEncode(t, []interface{}{
+			rlp.RawValue{0x82, 0x00, 0x01}, big.NewInt(1), emptyRoot, emptyCodeHash,
+		}),
+		// balance 5 as a one byte string instead of a single byte
+		"non-canonical balance": mustEncode(t, []interface{}{
+			uint64(1), rlp.RawValue{0x81, 0x05}, emptyRoot, emptyCodeHash,
+		}),
+	}
+	for name, value := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeAccount(value); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+	}
+}
+
+func mustEncode(t *testing.T, val interface{}) []byte {
+	t.Helper()
+	bz, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		t.Fatalf("Encoding: %+v", err)
+	}
+	return bz
+}
+
+// testState commits a state with 200 filler accounts plus whatever setup adds,
+// flushing all nodes to the returned database
+func testState(t *testing.T, setup func(*state.StateDB)) (ethdb.Database, *state.StateDB, common.Hash) {
+	diskdb := ethdb.NewMemDatabase()
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	if err != nil {
+		t.Fatalf("state.New: %+v", err)
+	}
+	for i := int64(0); i < 200; i++ {
+		statedb.SetBalance(common.BigToAddress(big.NewInt(i+1000)), big.NewInt(i))
+	}
+	setup(statedb)
+
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("Commit: %+v", err)
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("Flush: %+v", err)
+	}
+	return diskdb, statedb, root
+}
@@ -0,0 +1,136 @@
+package proof
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// ProofResult is the outcome of proving one key in a batch
+type ProofResult struct {
+	Key   []byte
+	Proof *Proof
+	// Err is set if no proof could be produced for Key
+	Err error
+}
+
+// ComputeProofs proves all keys against the same trie, spreading the work over
+// the given number of goroutines (or one per CPU if workers <= 0).
+// Results are returned in the same order as keys. If ctx is cancelled, keys
+// that were not processed yet get ctx.Err() as their error, which is then also
+// returned. A batch that completed before the cancellation returns no error.
+//
+// The trie must not be modified by anyone else while this runs.
+func ComputeProofs(ctx context.Context, tr *trie.Trie, keys [][]byte, workers int) ([]ProofResult, error) {
+	shared := &sharedTrie{tr: tr}
+	proofs := make([]*Proof, len(keys))
+	errs := fanOut(ctx, len(keys), workers, func(i int) error {
+		var err error
+		proofs[i], err = shared.computeProof(keys[i])
+		return err
+	})
+
+	results := make([]ProofResult, len(keys))
+	for i, key := range keys {
+		results[i] = ProofResult{Key: key, Proof: proofs[i], Err: errs[i]}
+	}
+	return results, cancelled(ctx, errs)
+}
+
+// VerifyProofs checks all proofs against the same root hash, spreading the work over
+// the given number of goroutines (or one per CPU if workers <= 0).
+// It returns one error per proof, in input order, nil meaning the proof is valid.
+// If ctx is cancelled, proofs that were not checked yet get ctx.Err(), which is then also returned.
+func VerifyProofs(ctx context.Context, proofs []*Proof, rootHash common.Hash, workers int) ([]error, error) {
+	errs := fanOut(ctx, len(proofs), workers, func(i int) error {
+		if proofs[i] == nil {
+			return fmt.Errorf("proof %d is nil", i)
+		}
+		return VerifyProof(proofs[i], rootHash)
+	})
+	return errs, cancelled(ctx, errs)
+}
+
+// cancelled returns ctx.Err() if any item got it rather than being processed
+func cancelled(ctx context.Context, errs []error) error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+	for _, e := range errs {
+		if e == err {
+			return err
+		}
+	}
+	return nil
+}
+
+// sharedTrie guards a trie used by many goroutines. Get may cache resolved nodes
+// in the trie, so it takes the write lock, while Prove only reads.
+type sharedTrie struct {
+	mu sync.RWMutex
+	tr *trie.Trie
+}
+
+func (s *sharedTrie) computeProof(key []byte) (*Proof, error) {
+	s.mu.Lock()
+	value := s.tr.Get(key)
+	s.mu.Unlock()
+	if value == nil {
+		return nil, fmt.Errorf("No value found for key %X", key)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return proveValue(s.tr, key, value)
+}
+
+// fanOut calls work(i) for every i in [0, n) on a pool of goroutines and returns
+// the error of each call by index. Once ctx is done, remaining indexes are not
+// processed and get ctx.Err() instead.
+func fanOut(ctx context.Context, n, workers int, work func(i int) error) []error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+
+	errs := make([]error, n)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = work(i)
+			}
+		}()
+	}
+
+	next := 0
+feed:
+	for ; next < n; next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := next; i < n; i++ {
+		errs[i] = ctx.Err()
+	}
+	return errs
+}
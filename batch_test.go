@@ -0,0 +1,118 @@
+package proof
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+// run these with -race to make sure the workers don't step on each other
+// (the pinned x/crypto sha3 trips checkptr, so add -gcflags=all=-d=checkptr=0)
+func TestComputeProofs(t *testing.T) {
+	tr, vals := randomTrie(t, 1000)
+	root := tr.Hash()
+
+	keys := make([][]byte, 0, len(vals)+1)
+	for _, v := range vals {
+		keys = append(keys, v.k)
+	}
+	// one key that is not in the trie, in the middle of the batch
+	missing := len(keys) / 2
+	keys = append(keys[:missing], append([][]byte{randBytes(32)}, keys[missing:]...)...)
+
+	results, err := ComputeProofs(context.Background(), tr, keys, 8)
+	if err != nil {
+		t.Fatalf("ComputeProofs: %+v", err)
+	}
+	if len(results) != len(keys) {
+		t.Fatalf("Got %d results for %d keys", len(results), len(keys))
+	}
+
+	proofs := make([]*Proof, len(results))
+	for i, res := range results {
+		if !bytes.Equal(keys[i], res.Key) {
+			t.Fatalf("Result %d is for key %X, expected %X", i, res.Key, keys[i])
+		}
+		if i == missing {
+			if res.Err == nil {
+				t.Fatalf("Expected error for missing key")
+			}
+			continue
+		}
+		if res.Err != nil {
+			t.Fatalf("Result %d: %+v", i, res.Err)
+		}
+		if !bytes.Equal(res.Key, res.Proof.Key) {
+			t.Fatalf("Proof %d is for key %X, expected %X", i, res.Proof.Key, res.Key)
+		}
+		proofs[i] = res.Proof
+	}
+
+	errs, err := VerifyProofs(context.Background(), proofs, root, 8)
+	if err != nil {
+		t.Fatalf("VerifyProofs: %+v", err)
+	}
+	for i, err := range errs {
+		if i == missing {
+			if err == nil {
+				t.Fatalf("Expected error for nil proof")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Invalid proof %d: %+v", i, err)
+		}
+	}
+}
+
+func TestBatchCancelled(t *testing.T) {
+	tr, vals := randomTrie(t, 100)
+	keys := make([][]byte, len(vals))
+	for i, v := range vals {
+		keys[i] = v.k
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := ComputeProofs(ctx, tr, keys, 4)
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	for i, res := range results {
+		if res.Err != context.Canceled {
+			t.Fatalf("Result %d: expected context.Canceled, got %v", i, res.Err)
+		}
+	}
+
+	errs, err := VerifyProofs(ctx, make([]*Proof, len(keys)), tr.Hash(), 4)
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	for i, err := range errs {
+		if err != context.Canceled {
+			t.Fatalf("Proof %d: expected context.Canceled, got %v", i, err)
+		}
+	}
+}
+
+func TestBatchCancelledAfterCompletion(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the context is done by the time the batch returns, but every item was processed
+	errs := fanOut(ctx, 3, 1, func(i int) error {
+		if i == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if err := cancelled(ctx, errs); err != nil {
+		t.Fatalf("Expected no error for a complete batch, got %v", err)
+	}
+
+	errs[1] = ctx.Err()
+	if err := cancelled(ctx, errs); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// CheckCanonicalNode makes sure raw is the canonical encoding geth would produce
+// for the node it holds. decodeNode accepts some other encodings, and those hash
+// differently than the logical node they decode to.
+func CheckCanonicalNode(raw []byte) error {
+	return checkCanonical(raw, false)
+}
+
+// checkCanonical makes sure buf is exactly one canonically encoded node. On top of
+// the minimal RLP sizes rlp.Split already enforces, this means:
+//   - no trailing data after the node
+//   - minimal hex-prefix keys, and no short node with an empty path (leaves may
+//     have just the terminator, when their key ends right below a full node)
+//   - extensions point to a full node, leaves hold a non-empty value
+//   - full nodes have at least two children (counting their value)
+//   - embedded nodes are under 32 bytes, longer ones are referenced by hash
+func checkCanonical(buf []byte, embedded bool) error {
+	elems, rest, err := rlp.SplitList(buf)
+	if err != nil {
+		return fmt.Errorf("decode error: %v", err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%d bytes of trailing data after node", len(rest))
+	}
+	if embedded && len(buf) >= hashLen {
+		return fmt.Errorf("embedded node has %d bytes, it should be referenced by hash", len(buf))
+	}
+
+	switch c, _ := rlp.CountValues(elems); c {
+	case 2:
+		return checkCanonicalShort(elems)
+	case 17:
+		return checkCanonicalFull(elems)
+	default:
+		return fmt.Errorf("invalid number of list elements: %v", c)
+	}
+}
+
+func checkCanonicalShort(elems []byte) error {
+	compact, rest, err := rlp.SplitString(elems)
+	if err != nil {
+		return err
+	}
+	key := compactToHex(compact)
+	if !bytes.Equal(hexToCompact(key), compact) {
+		return fmt.Errorf("non-minimal hex-prefix key %X", compact)
+	}
+	if !hasTerm(key) {
+		if len(key) == 0 {
+			return fmt.Errorf("extension node with an empty key")
+		}
+		// two short nodes in a row would have been merged into one
+		if kind, _, _, _ := rlp.Split(rest); kind == rlp.List {
+			if err := checkCanonical(rest, true); err != nil {
+				return wrapError(err, "val")
+			}
+			if c, _ := countList(rest); c != 17 {
+				return fmt.Errorf("extension node must point to a full node")
+			}
+			return nil
+		}
+		return checkRef(rest, false)
+	}
+	val, _, err := rlp.SplitString(rest)
+	if err != nil {
+		return fmt.Errorf("invalid value node: %v", err)
+	}
+	if len(val) == 0 {
+		return fmt.Errorf("leaf node with an empty value")
+	}
+	return nil
+}
+
+func checkCanonicalFull(elems []byte) error {
+	children := 0
+	for i := 0; i < 16; i++ {
+		_, _, rest, err := rlp.Split(elems)
+		if err != nil {
+			return wrapError(err, fmt.Sprintf("[%d]", i))
+		}
+		ref := elems[:len(elems)-len(rest)]
+		if err := checkRef(ref, true); err != nil {
+			return wrapError(err, fmt.Sprintf("[%d]", i))
+		}
+		if !bytes.Equal(ref, emptyRef) {
+			children++
+		}
+		elems = rest
+	}
+	val, _, err := rlp.SplitString(elems)
+	if err != nil {
+		return err
+	}
+	if len(val) > 0 {
+		children++
+	}
+	if children < 2 {
+		return fmt.Errorf("full node with %d children, it should be a short node", children)
+	}
+	return nil
+}
+
+// checkRef makes sure ref is a hash, an embedded node or (if allowEmpty) empty
+func checkRef(ref []byte, allowEmpty bool) error {
+	kind, val, _, err := rlp.Split(ref)
+	if err != nil {
+		return err
+	}
+	switch {
+	case kind == rlp.List:
+		return checkCanonical(ref, true)
+	case kind == rlp.String && len(val) == common.HashLength:
+		return nil
+	case kind == rlp.String && len(val) == 0 && allowEmpty:
+		return nil
+	default:
+		return fmt.Errorf("invalid reference %X", ref)
+	}
+}
+
+// countList returns the number of elements in the RLP list buf
+func countList(buf []byte) (int, error) {
+	elems, _, err := rlp.SplitList(buf)
+	if err != nil {
+		return 0, err
+	}
+	return rlp.CountValues(elems)
+}
+
+// VerifyProofStrict checks proof like VerifyProof, or VerifyAbsence if it has no value,
+// and also makes sure every node in it has the canonical encoding
+func VerifyProofStrict(proof *Proof, rootHash common.Hash) error {
+	for i, step := range proof.Steps {
+		if err := CheckCanonicalNode(step.Raw); err != nil {
+			return fmt.Errorf("step %d is not canonical: %v", i, err)
+		}
+	}
+	_, err := verifyProofOrAbsence(proof, rootHash)
+	return err
+}
@@ -0,0 +1,109 @@
+package proof
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestCheckCanonicalNode(t *testing.T) {
+	hash := bytes.Repeat([]byte{0xab}, 32)
+	leaf := mustEncode(t, []interface{}{[]byte{0x20}, []byte("value")})
+	full := func(children map[int]interface{}, value []byte) []interface{} {
+		n := make([]interface{}, 17)
+		for i := range n {
+			n[i] = []byte{}
+		}
+		for i, c := range children {
+			n[i] = c
+		}
+		n[16] = value
+		return n
+	}
+
+	cases := map[string]struct {
+		raw   []byte
+		isErr bool
+	}{
+		"leaf":                  {raw: leaf},
+		"leaf with odd key":     {raw: mustEncode(t, []interface{}{[]byte{0x31, 0x23}, []byte("value")})},
+		"extension":             {raw: mustEncode(t, []interface{}{[]byte{0x00, 0x12}, hash})},
+		"full node":             {raw: mustEncode(t, full(map[int]interface{}{1: hash, 7: hash}, nil))},
+		"full node with value":  {raw: mustEncode(t, full(map[int]interface{}{1: hash}, []byte("v")))},
+		"embedded leaf":         {raw: mustEncode(t, full(map[int]interface{}{1: rlp.RawValue(leaf), 2: hash}, nil))},
+		"trailing data":         {raw: append(append([]byte{}, leaf...), 0x00), isErr: true},
+		"padding nibble set":    {raw: mustEncode(t, []interface{}{[]byte{0x21, 0x23}, []byte("value")}), isErr: true},
+		"unknown key flag":      {raw: mustEncode(t, []interface{}{[]byte{0x41, 0x23}, []byte("value")}), isErr: true},
+		"empty compact key":     {raw: mustEncode(t, []interface{}{[]byte{}, hash}), isErr: true},
+		"empty extension path":  {raw: mustEncode(t, []interface{}{[]byte{0x00}, hash}), isErr: true},
+		"leaf without value":    {raw: mustEncode(t, []interface{}{[]byte{0x20}, []byte{}}), isErr: true},
+		"extension to nothing":  {raw: mustEncode(t, []interface{}{[]byte{0x00, 0x12}, []byte{}}), isErr: true},
+		"extension to leaf":     {raw: mustEncode(t, []interface{}{[]byte{0x00, 0x12}, rlp.RawValue(leaf)}), isErr: true},
+		"short hash":            {raw: mustEncode(t, full(map[int]interface{}{1: hash[:31], 2: hash}, nil)), isErr: true},
+		"one child":             {raw: mustEncode(t, full(map[int]interface{}{1: hash}, nil)), isErr: true},
+		"value only":            {raw: mustEncode(t, full(nil, []byte("v"))), isErr: true},
+		"32 byte embedded node": {raw: mustEncode(t, full(map[int]interface{}{1: rlp.RawValue(mustEncode(t, []interface{}{[]byte{0x20}, bytes.Repeat([]byte{1}, 29)})), 2: hash}, nil)), isErr: true},
+		"three elements":        {raw: mustEncode(t, []interface{}{[]byte{0x20}, []byte("a"), []byte("b")}), isErr: true},
+		"long form size":        {raw: append([]byte{0xf8, byte(len(leaf) - 1)}, leaf[1:]...), isErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := CheckCanonicalNode(tc.raw)
+			if tc.isErr && err == nil {
+				t.Fatalf("Expected error for %X", tc.raw)
+			}
+			if !tc.isErr && err != nil {
+				t.Fatalf("CheckCanonicalNode: %+v", err)
+			}
+		})
+	}
+}
+
+func TestCanonicalTrieNodes(t *testing.T) {
+	diskdb, _, _ := diskTrie(t, 1000)
+	keys := diskdb.(*ethdb.MemDatabase).Keys()
+	for _, key := range keys {
+		raw, _ := diskdb.Get(key)
+		if err := CheckCanonicalNode(raw); err != nil {
+			t.Fatalf("Node %X: %+v", key, err)
+		}
+	}
+	if len(keys) == 0 {
+		t.Fatalf("No trie nodes found")
+	}
+}
+
+func TestVerifyProofStrict(t *testing.T) {
+	diskdb, tr, vals := diskTrie(t, 1000)
+	root := tr.Hash()
+	for _, key := range [][]byte{vals[3].k, randBytes(32)} {
+		proof, err := computeProofOrAbsence(diskdb, root, key)
+		if err != nil {
+			t.Fatalf("computeProofOrAbsence: %+v", err)
+		}
+		if err := VerifyProofStrict(proof, root); err != nil {
+			t.Fatalf("VerifyProofStrict: %+v", err)
+		}
+	}
+
+	// a root over a leaf with trailing data passes the lenient check only
+	key := []byte("key")
+	compact := hexToCompact(keybytesToHex(key))
+	raw := append(mustEncode(t, []interface{}{compact, []byte("value")}), 0x00)
+	hash := makeHashNode(raw)
+	proof := &Proof{
+		Steps: []Step{{Step: mustDecodeNode(hash, raw, 0), Hash: hash, Raw: raw}},
+		Key:   key,
+		Value: []byte("value"),
+	}
+	if err := VerifyProof(proof, common.BytesToHash(hash)); err != nil {
+		t.Fatalf("VerifyProof: %+v", err)
+	}
+	if err := VerifyProofStrict(proof, common.BytesToHash(hash)); err == nil {
+		t.Fatalf("Expected error for non-canonical node")
+	}
+}
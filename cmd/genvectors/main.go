@@ -0,0 +1,233 @@
+// genvectors writes proof test vectors as JSON, for verifiers in other languages
+// to test against this implementation. The tries are built from a seeded random
+// source, so the same seed always gives the same file.
+//
+//	genvectors -seed 1 -n 5 -o vectors.json
+//
+// Each vector has the trie root, the key, the value (null for absence proofs)
+// and the proof, as the list of raw RLP nodes from the root down.
+// testdata/vectors.json is the output for the default flags.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+
+	proof "github.com/confio/proofs-ethereum"
+)
+
+// Kinds of vectors
+const (
+	// Membership proves a value held by a leaf node
+	Membership = "membership"
+	// Absence proves a key has no value
+	Absence = "absence"
+	// Embedded proves a value in a node embedded in its parent, rather than referenced by hash
+	Embedded = "embedded"
+	// BranchValue proves a value held in slot 16 of a branch node, for a key that is a prefix of others
+	BranchValue = "branch-value"
+)
+
+var kinds = []string{Membership, Absence, Embedded, BranchValue}
+
+func main() {
+	seed := flag.Int64("seed", 1, "seed of the random tries")
+	n := flag.Int("n", 4, "number of vectors of each kind")
+	out := flag.String("o", "", "file to write the vectors to (default stdout)")
+	flag.Parse()
+
+	fixture, err := generate(*seed, *n)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := write(w, fixture); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Fixture is the JSON file genvectors writes
+type Fixture struct {
+	Seed    int64    `json:"seed"`
+	Vectors []Vector `json:"vectors"`
+}
+
+// Vector is one proof to check
+type Vector struct {
+	Name  string          `json:"name"`
+	Kind  string          `json:"kind"`
+	Root  common.Hash     `json:"root"`
+	Key   hexutil.Bytes   `json:"key"`
+	Value *hexutil.Bytes  `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+func write(w io.Writer, fixture *Fixture) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fixture)
+}
+
+// generate builds the tries for every kind of vector from seed, and returns n vectors
+// of each kind, checked with the Go verifier
+func generate(seed int64, n int) (*Fixture, error) {
+	rnd := rand.New(rand.NewSource(seed))
+	fixture := &Fixture{Seed: seed}
+	found := make(map[string]int)
+	add := func(v Vector) {
+		if found[v.Kind] < n {
+			v.Name = fmt.Sprintf("%s-%d", v.Kind, found[v.Kind])
+			found[v.Kind]++
+			fixture.Vectors = append(fixture.Vectors, v)
+		}
+	}
+
+	tries := []struct {
+		// keys and values are random, of up to maxKey and maxValue bytes
+		items, maxKey, maxValue int
+		// prefixes adds that many keys that are a prefix of another key
+		prefixes int
+	}{
+		// a large trie, hashing every node
+		{items: 500, maxKey: 32, maxValue: 64},
+		// a small one with small values, which end up embedded in their parents
+		{items: 20, maxKey: 2, maxValue: 3},
+		// keys that are prefixes of others, with values big enough not to be embedded
+		{items: 50, maxKey: 8, maxValue: 40, prefixes: 20},
+	}
+	for _, spec := range tries {
+		db, root, keys, err := randomTrie(rnd, spec.items, spec.maxKey, spec.maxValue, spec.prefixes)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			p, err := proof.ComputeProofFromDB(db, root, key)
+			if err != nil {
+				return nil, err
+			}
+			v, err := newVector(p, root)
+			if err != nil {
+				return nil, err
+			}
+			add(v)
+		}
+		for i := 0; i < n; i++ {
+			key := randBytes(rnd, 1+rnd.Intn(spec.maxKey))
+			p, err := proof.ComputeAbsenceProofFromDB(db, root, key)
+			if err != nil {
+				// the random key happens to be in the trie
+				continue
+			}
+			v, err := newVector(p, root)
+			if err != nil {
+				return nil, err
+			}
+			add(v)
+		}
+	}
+
+	for _, kind := range kinds {
+		if found[kind] < n {
+			return nil, fmt.Errorf("only found %d %s vectors with seed %d", found[kind], kind, seed)
+		}
+	}
+	return fixture, nil
+}
+
+// newVector checks p and finds which kind of vector it is
+func newVector(p *proof.Proof, root common.Hash) (Vector, error) {
+	if err := proof.VerifyProofStrict(p, root); err != nil {
+		return Vector{}, fmt.Errorf("generated an invalid proof for key %X: %v", p.Key, err)
+	}
+	v := Vector{Root: root, Key: p.Key}
+	for _, step := range p.Steps {
+		v.Proof = append(v.Proof, step.Raw)
+	}
+
+	if p.Value == nil {
+		v.Kind = Absence
+		return v, nil
+	}
+	value := hexutil.Bytes(p.Value)
+	v.Value = &value
+	last := p.Steps[len(p.Steps)-1]
+	stats, err := proof.AnalyzeProof(&proof.Proof{Steps: []proof.Step{last}})
+	if err != nil {
+		return Vector{}, err
+	}
+	switch {
+	case stats.Kinds[proof.LeafNode] == 1:
+		v.Kind = Membership
+	case stats.Kinds[proof.BranchNode] == 1 && last.Index == 16:
+		v.Kind = BranchValue
+	default:
+		v.Kind = Embedded
+	}
+	return v, nil
+}
+
+// randomTrie commits a trie of n random items to a new database, and returns the
+// keys in it. Each of the first prefixes keys also gets a shorter key that is its prefix.
+func randomTrie(rnd *rand.Rand, n, maxKey, maxValue, prefixes int) (ethdb.Database, common.Hash, [][]byte, error) {
+	diskdb := ethdb.NewMemDatabase()
+	triedb := trie.NewDatabase(diskdb)
+	tr, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		return nil, common.Hash{}, nil, err
+	}
+
+	var keys [][]byte
+	add := func(key []byte) {
+		for _, k := range keys {
+			if bytes.Equal(k, key) {
+				return
+			}
+		}
+		tr.Update(key, randBytes(rnd, 1+rnd.Intn(maxValue)))
+		keys = append(keys, key)
+	}
+	for i := 0; i < n; i++ {
+		key := randBytes(rnd, 1+rnd.Intn(maxKey))
+		add(key)
+		if i < prefixes && len(key) > 1 {
+			add(key[:1+rnd.Intn(len(key)-1)])
+		}
+	}
+
+	root, err := tr.Commit(nil)
+	if err != nil {
+		return nil, common.Hash{}, nil, err
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		return nil, common.Hash{}, nil, err
+	}
+	return diskdb, root, keys, nil
+}
+
+func randBytes(rnd *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	rnd.Read(b)
+	return b
+}
@@ -0,0 +1,197 @@
+// proofdiff compares the eth_getProof responses for one account at two blocks, and
+// shows which nodes changed along the path to the account and to each storage slot.
+//
+//	proofdiff old.json new.json
+//
+// Every level of the proofs is listed with the node in each, by kind and the start of
+// its hash, and the children of branch nodes next to the path that changed.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	proof "github.com/confio/proofs-ethereum"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintf(os.Stderr, "Usage: %s old.json new.json\n\nReads two eth_getProof responses for the same account.\n", os.Args[0])
+		os.Exit(2)
+	}
+	old, err := readFile(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	cur, err := readFile(os.Args[2])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := run(old, cur, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func readFile(name string) (*proof.GetProofResult, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	result, err := proof.ReadGetProofResult(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return result, nil
+}
+
+func run(old, cur *proof.GetProofResult, out io.Writer) error {
+	if old.Address != cur.Address {
+		return fmt.Errorf("proofs are for different accounts %s and %s", old.Address.Hex(), cur.Address.Hex())
+	}
+	key := crypto.Keccak256(old.Address[:])
+	diff, err := proof.DiffProofs(newProof(key, old.AccountProof), newProof(key, cur.AccountProof))
+	if err != nil {
+		return fmt.Errorf("account %s: %v", old.Address.Hex(), err)
+	}
+	if err := printDiff(out, fmt.Sprintf("account %s", old.Address.Hex()), diff, accountValue); err != nil {
+		return err
+	}
+
+	slots := make(map[common.Hash][]hexutil.Bytes)
+	for _, storage := range cur.StorageProof {
+		slots[common.HexToHash(storage.Key)] = storage.Proof
+	}
+	for _, storage := range old.StorageProof {
+		slot := common.HexToHash(storage.Key)
+		nodes, ok := slots[slot]
+		if !ok {
+			fmt.Fprintf(out, "\nslot %s is only in the old proof\n", slot.Hex())
+			continue
+		}
+		delete(slots, slot)
+		key := proof.SlotAt(slot).TrieKey()
+		diff, err := proof.DiffProofs(newProof(key, storage.Proof), newProof(key, nodes))
+		if err != nil {
+			return fmt.Errorf("slot %s: %v", slot.Hex(), err)
+		}
+		if err := printDiff(out, fmt.Sprintf("slot %s", slot.Hex()), diff, storageValue); err != nil {
+			return err
+		}
+	}
+	for _, storage := range cur.StorageProof {
+		if slot := common.HexToHash(storage.Key); slots[slot] != nil {
+			fmt.Fprintf(out, "\nslot %s is only in the new proof\n", slot.Hex())
+		}
+	}
+	return nil
+}
+
+// newProof takes the value from the nodes, as eth_getProof only returns it decoded
+func newProof(key []byte, nodes []hexutil.Bytes) *proof.Proof {
+	p := &proof.Proof{Key: key, Steps: make([]proof.Step, len(nodes))}
+	for i, node := range nodes {
+		p.Steps[i].Raw = node
+	}
+	return p
+}
+
+func printDiff(out io.Writer, name string, diff *proof.ProofDiff, value func([]byte) string) error {
+	fmt.Fprintf(out, "\n%s\n", name)
+	switch {
+	case diff.Divergence < 0:
+		fmt.Fprintf(out, "unchanged under root %X\n", diff.OldRoot)
+		return nil
+	case diff.Convergence < 0:
+		fmt.Fprintf(out, "root %X -> %X, changed down to the last level\n", diff.OldRoot, diff.NewRoot)
+	default:
+		fmt.Fprintf(out, "root %X -> %X, changed down to level %d\n", diff.OldRoot, diff.NewRoot, diff.Convergence-1)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "LEVEL\tPATH\tOLD\tNEW\tSIBLINGS")
+	for i, level := range diff.Levels {
+		path := ""
+		if level.Old != nil {
+			path = nibbles(level.Old.Path)
+		} else {
+			path = nibbles(level.New.Path)
+		}
+		newNode := describe(level.New)
+		if !level.Changed() {
+			newNode = "same"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i, path, describe(level.Old), newNode, siblings(level.Siblings))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if diff.ValueChanged() {
+		fmt.Fprintf(out, "value %s -> %s\n", value(diff.OldValue), value(diff.NewValue))
+	} else {
+		fmt.Fprintf(out, "value unchanged %s\n", value(diff.OldValue))
+	}
+	return nil
+}
+
+// describe prints the kind of a node and the start of its hash
+func describe(n *proof.DiffNode) string {
+	if n == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%s %X", n.Kind, n.Hash[:4])
+}
+
+// siblings lists the changed children by nibble
+func siblings(diffs []proof.SiblingDiff) string {
+	var s []string
+	for _, d := range diffs {
+		s = append(s, fmt.Sprintf("%x", d.Index))
+	}
+	return strings.Join(s, ",")
+}
+
+func nibbles(path []byte) string {
+	if len(path) == 0 {
+		return "-"
+	}
+	var s strings.Builder
+	for _, n := range path {
+		fmt.Fprintf(&s, "%x", n)
+	}
+	return s.String()
+}
+
+func accountValue(value []byte) string {
+	if value == nil {
+		return "(no account)"
+	}
+	account, err := proof.DecodeAccount(value)
+	if err != nil {
+		return fmt.Sprintf("%X (%v)", value, err)
+	}
+	return fmt.Sprintf("{nonce %d, balance %s, storage %X, code %X}",
+		account.Nonce, account.Balance, account.StorageRoot[:4], account.CodeHash[:4])
+}
+
+func storageValue(value []byte) string {
+	if value == nil {
+		return "0"
+	}
+	word, err := proof.DecodeStorageValue(value)
+	if err != nil {
+		return fmt.Sprintf("%X (%v)", value, err)
+	}
+	return word.Hash().Hex()
+}
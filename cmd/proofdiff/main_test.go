@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+
+	proof "github.com/confio/proofs-ethereum"
+)
+
+func TestRun(t *testing.T) {
+	diskdb := ethdb.NewMemDatabase()
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	if err != nil {
+		t.Fatalf("state.New: %+v", err)
+	}
+	commit := func() common.Hash {
+		root, err := statedb.Commit(false)
+		if err != nil {
+			t.Fatalf("Commit: %+v", err)
+		}
+		if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+			t.Fatalf("Flush: %+v", err)
+		}
+		return root
+	}
+	getProof := func(root common.Hash, addr common.Address, slots ...common.Hash) *proof.GetProofResult {
+		account, err := proof.ComputeAccountProof(diskdb, root, addr)
+		if err != nil {
+			t.Fatalf("ComputeAccountProof: %+v", err)
+		}
+		result := &proof.GetProofResult{Address: addr, AccountProof: nodes(account.Proof)}
+		for _, slot := range slots {
+			p, err := proof.ComputeStorageProof(diskdb, account.Account.StorageRoot, proof.SlotAt(slot))
+			if err != nil {
+				t.Fatalf("ComputeStorageProof: %+v", err)
+			}
+			result.StorageProof = append(result.StorageProof, proof.StorageProofResult{Key: hexutil.EncodeBig(slot.Big()), Proof: nodes(p)})
+		}
+		return result
+	}
+
+	addr := common.HexToAddress("0xdeadbeef")
+	for i := int64(0); i < 100; i++ {
+		statedb.SetBalance(common.BigToAddress(big.NewInt(i+1000)), big.NewInt(i))
+		statedb.SetState(addr, common.BigToHash(big.NewInt(i)), common.BigToHash(big.NewInt(i+1)))
+	}
+	oldRoot := commit()
+	statedb.SetState(addr, common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(42)))
+	newRoot := commit()
+
+	slots := []common.Hash{common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(2))}
+	var out bytes.Buffer
+	if err := run(getProof(oldRoot, addr, slots...), getProof(newRoot, addr, slots...), &out); err != nil {
+		t.Fatalf("run: %+v", err)
+	}
+	report := out.String()
+	for _, want := range []string{
+		"account 0x00000000000000000000000000000000DeaDBeef\nroot ",
+		"slot 0x0000000000000000000000000000000000000000000000000000000000000001\nroot ",
+		"value 0x0000000000000000000000000000000000000000000000000000000000000002 -> 0x000000000000000000000000000000000000000000000000000000000000002a",
+		"value unchanged 0x0000000000000000000000000000000000000000000000000000000000000003",
+		"value {nonce 0, balance 0, storage ",
+	} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("Report misses %q:\n%s", want, report)
+		}
+	}
+
+	// an account that didn't change
+	other := common.BigToAddress(big.NewInt(1010))
+	out.Reset()
+	if err := run(getProof(oldRoot, other), getProof(oldRoot, other), &out); err != nil {
+		t.Fatalf("run: %+v", err)
+	}
+	if !strings.Contains(out.String(), "unchanged under root") {
+		t.Fatalf("Expected no change:\n%s", out.String())
+	}
+
+	if err := run(getProof(oldRoot, addr), getProof(newRoot, other), &out); err == nil {
+		t.Fatalf("Expected error for different accounts")
+	}
+}
+
+func nodes(p *proof.Proof) []hexutil.Bytes {
+	res := make([]hexutil.Bytes, len(p.Steps))
+	for i, step := range p.Steps {
+		res[i] = step.Raw
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+// proofstats reports the size of the proofs in an eth_getProof response, and what
+// relaying them to the on-chain verifier would cost in calldata gas.
+//
+//	curl -s -X POST -H 'Content-Type: application/json' \
+//	  --data '{"jsonrpc":"2.0","id":1,"method":"eth_getProof","params":["0x...",["0x0"],"latest"]}' \
+//	  $RPC | proofstats
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	proof "github.com/confio/proofs-ethereum"
+)
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the stats as JSON, eg. to track proof size over time")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-json] [file]\n\nReads an eth_getProof response from file, or stdin.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	in := os.Stdin
+	if flag.NArg() > 0 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+	if err := run(in, os.Stdout, *asJSON); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// namedStats are the stats of one proof, as printed with -json
+type namedStats struct {
+	Name string
+	*proof.ProofStats
+}
+
+func run(in io.Reader, out io.Writer, asJSON bool) error {
+	result, err := proof.ReadGetProofResult(in)
+	if err != nil {
+		return err
+	}
+
+	var all []namedStats
+	add := func(name string, nodes []hexutil.Bytes) error {
+		p := &proof.Proof{Steps: make([]proof.Step, len(nodes))}
+		for i, node := range nodes {
+			p.Steps[i].Raw = node
+		}
+		stats, err := proof.AnalyzeProof(p)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		all = append(all, namedStats{Name: name, ProofStats: stats})
+		return nil
+	}
+	if err := add(fmt.Sprintf("account %s", hexutil.Encode(result.Address[:])), result.AccountProof); err != nil {
+		return err
+	}
+	for _, storage := range result.StorageProof {
+		if err := add(fmt.Sprintf("slot %s", storage.Key), storage.Proof); err != nil {
+			return err
+		}
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(all)
+	}
+	return printReport(out, all)
+}
+
+func printReport(out io.Writer, all []namedStats) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PROOF\tDEPTH\tBYTES\tNODES\tHASHED\tEMBEDDED\tCALLDATA\tGAS")
+	var bytes, calldata int
+	var gas uint64
+	for _, s := range all {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\t%d\t%d\t%d\n", s.Name, s.Depth, s.TotalBytes, kinds(s.ProofStats),
+			s.HashedChildren, s.EmbeddedChildren, s.CalldataBytes, s.CalldataGas)
+		bytes += s.TotalBytes
+		calldata += s.CalldataBytes
+		gas += s.CalldataGas
+	}
+	fmt.Fprintf(w, "total\t\t%d\t\t\t\t%d\t%d\n", bytes, calldata, gas)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, s := range all {
+		fmt.Fprintf(out, "\n%s step bytes: %v\n", s.Name, s.StepBytes)
+	}
+	return nil
+}
+
+// kinds prints the node type distribution, eg. "3 branch, 1 leaf"
+func kinds(s *proof.ProofStats) string {
+	var keys []int
+	for kind := range s.Kinds {
+		keys = append(keys, int(kind))
+	}
+	sort.Ints(keys)
+	var res string
+	for i, k := range keys {
+		if i > 0 {
+			res += ", "
+		}
+		res += fmt.Sprintf("%d %s", s.Kinds[proof.NodeKind(k)], proof.NodeKind(k))
+	}
+	return res
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+
+	proof "github.com/confio/proofs-ethereum"
+)
+
+func TestRun(t *testing.T) {
+	tr, err := trie.New(common.Hash{}, trie.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("trie.New: %+v", err)
+	}
+	for i := 0; i < 100; i++ {
+		tr.Update([]byte(fmt.Sprintf("key %d", i)), bytes.Repeat([]byte{byte(i)}, 40))
+	}
+	p, err := proof.ComputeProof(tr, []byte("key 42"))
+	if err != nil {
+		t.Fatalf("ComputeProof: %+v", err)
+	}
+	nodes := make([]hexutil.Bytes, len(p.Steps))
+	for i, step := range p.Steps {
+		nodes[i] = step.Raw
+	}
+	result := map[string]interface{}{
+		"address":      "0x00000000000000000000000000000000deadbeef",
+		"accountProof": nodes,
+		"storageProof": []interface{}{
+			map[string]interface{}{"key": "0x0", "value": "0x0", "proof": nodes[:1]},
+		},
+	}
+	response, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	if err != nil {
+		t.Fatalf("Marshal: %+v", err)
+	}
+	stats, err := proof.AnalyzeProof(p)
+	if err != nil {
+		t.Fatalf("AnalyzeProof: %+v", err)
+	}
+
+	var out bytes.Buffer
+	if err := run(bytes.NewReader(response), &out, false); err != nil {
+		t.Fatalf("run: %+v", err)
+	}
+	if !strings.Contains(out.String(), fmt.Sprintf("account 0x00000000000000000000000000000000deadbeef  %d ", stats.Depth)) {
+		t.Fatalf("Account proof missing from report:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), fmt.Sprintf("step bytes: %v", stats.StepBytes)) {
+		t.Fatalf("Step bytes missing from report:\n%s", out.String())
+	}
+
+	// -json takes the result on its own too
+	raw, _ := json.Marshal(result)
+	out.Reset()
+	if err := run(bytes.NewReader(raw), &out, true); err != nil {
+		t.Fatalf("run: %+v", err)
+	}
+	var parsed []struct {
+		Name        string
+		Kinds       map[string]int
+		CalldataGas uint64
+	}
+	if err := json.Unmarshal(out.Bytes(), &parsed); err != nil {
+		t.Fatalf("Unmarshal: %+v\n%s", err, out.String())
+	}
+	if len(parsed) != 2 || parsed[0].CalldataGas != stats.CalldataGas || parsed[1].Kinds["extension"] != 1 {
+		t.Fatalf("Unexpected output:\n%s", out.String())
+	}
+
+	if err := run(strings.NewReader(`{"jsonrpc":"2.0","id":1,"error":{"message":"header not found"}}`), &out, false); err == nil {
+		t.Fatalf("Expected error for a failed call")
+	}
+}
@@ -0,0 +1,36 @@
+package proof
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CodeProof is a verified account state together with the code it runs.
+// Code is not stored in the state trie, only its hash, so it is checked against that.
+type CodeProof struct {
+	*AccountProof
+	Code []byte
+}
+
+// VerifyCode checks proof as VerifyAccount does, then that code hashes to the proven
+// code hash of addr. A missing account (or one without code) only matches empty code.
+func VerifyCode(proof *Proof, stateRoot common.Hash, addr common.Address, code []byte) (*CodeProof, error) {
+	account, err := VerifyAccount(proof, stateRoot, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := account.VerifyCode(code); err != nil {
+		return nil, err
+	}
+	return &CodeProof{AccountProof: account, Code: code}, nil
+}
+
+// VerifyCode checks that code is what the proven account runs
+func (a *AccountProof) VerifyCode(code []byte) error {
+	got := common.BytesToHash(makeHashNode(code))
+	if got != a.Account.CodeHash {
+		return fmt.Errorf("code of %X hashes to %X, account has code hash %X", a.Address, got, a.Account.CodeHash)
+	}
+	return nil
+}
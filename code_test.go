@@ -0,0 +1,56 @@
+package proof
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+)
+
+func TestVerifyCode(t *testing.T) {
+	contract := common.HexToAddress("0xc0de")
+	user := common.HexToAddress("0x05e7")
+	missing := common.HexToAddress("0xdead")
+	code := []byte{0x60, 0x80, 0x60, 0x40, 0x52, 0x00}
+
+	diskdb, _, root := testState(t, func(statedb *state.StateDB) {
+		statedb.SetCode(contract, code)
+		statedb.SetNonce(user, 1)
+	})
+
+	cases := map[string]struct {
+		addr  common.Address
+		code  []byte
+		isErr bool
+	}{
+		"contract":             {addr: contract, code: code},
+		"contract, wrong code": {addr: contract, code: append([]byte{0x00}, code...), isErr: true},
+		"contract, no code":    {addr: contract, code: nil, isErr: true},
+		"user":                 {addr: user, code: nil},
+		"user, with code":      {addr: user, code: code, isErr: true},
+		"missing":              {addr: missing, code: nil},
+		"missing, with code":   {addr: missing, code: code, isErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			computed, err := ComputeAccountProof(diskdb, root, tc.addr)
+			if err != nil {
+				t.Fatalf("ComputeAccountProof: %+v", err)
+			}
+			verified, err := VerifyCode(computed.Proof, root, tc.addr, tc.code)
+			if tc.isErr {
+				if err == nil {
+					t.Fatalf("Expected error, but was <nil>")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyCode: %+v", err)
+			}
+			if string(verified.Code) != string(tc.code) || verified.Address != tc.addr {
+				t.Fatalf("Unexpected result %+v", verified)
+			}
+		})
+	}
+}
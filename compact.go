@@ -0,0 +1,184 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// emptyRef is the RLP of the empty string, which takes the place of elided references
+var emptyRef = []byte{0x80}
+
+// CompactProof is a Proof without anything the verifier can derive itself.
+// Each node references the next one by hash, which is the hash of the next node,
+// so that reference is replaced by an empty string. The key tells which child
+// was followed, and the hash of every step comes from hashing its node.
+type CompactProof struct {
+	Key []byte
+	// Value is nil for an absence proof
+	Value []byte
+	// Nodes are the raw nodes, root first, with the reference to the next node elided
+	Nodes [][]byte
+}
+
+// Compact drops the re-derivable hashes from proof. Expand gives it back.
+func (p *Proof) Compact() (*CompactProof, error) {
+	nodes := make([][]byte, len(p.Steps))
+	for i, step := range p.Steps {
+		if len(step.Raw) == 0 {
+			return nil, fmt.Errorf("step %d is missing the raw node encoding", i)
+		}
+		nodes[i] = step.Raw
+	}
+	refs, err := followedRefs(nodes, p.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	compact := &CompactProof{Key: p.Key, Value: p.Value, Nodes: make([][]byte, len(nodes))}
+	for i, raw := range nodes {
+		if i == len(nodes)-1 {
+			compact.Nodes[i] = append([]byte{}, raw...)
+			break
+		}
+		ref := hashRef(makeHashNode(nodes[i+1]))
+		compact.Nodes[i], err = replaceRef(raw, refs[i], ref, emptyRef)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %v", i, err)
+		}
+	}
+	return compact, nil
+}
+
+// Expand recomputes the elided hashes, from the last node up to the root,
+// and returns the standard Proof
+func (c *CompactProof) Expand() (*Proof, error) {
+	refs, err := followedRefs(c.Nodes, c.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]Step, len(c.Nodes))
+	for i := len(c.Nodes) - 1; i >= 0; i-- {
+		raw := append([]byte{}, c.Nodes[i]...)
+		if i < len(c.Nodes)-1 {
+			ref := hashRef(steps[i+1].Hash)
+			raw, err = replaceRef(raw, refs[i], emptyRef, ref)
+			if err != nil {
+				return nil, fmt.Errorf("step %d: %v", i, err)
+			}
+		}
+		hash := makeHashNode(raw)
+		decoded, err := decodeNode(hash, raw, 0)
+		if err != nil {
+			return nil, fmt.Errorf("step %d cannot decode raw node: %v", i, err)
+		}
+		steps[i] = Step{Step: decoded, Hash: hash, Raw: raw}
+	}
+
+	if c.Value == nil {
+		return buildAbsenceProof(c.Key, steps), nil
+	}
+	return buildProof(c.Key, c.Value, steps)
+}
+
+// VerifyCompactProof expands proof and checks it shows its value (or absence) under rootHash
+func VerifyCompactProof(proof *CompactProof, rootHash common.Hash) error {
+	expanded, err := proof.Expand()
+	if err != nil {
+		return err
+	}
+	_, err = verifyProofOrAbsence(expanded, rootHash)
+	return err
+}
+
+// Bytes is the RLP encoding of the compact proof, eg. to send it as calldata
+func (c *CompactProof) Bytes() ([]byte, error) {
+	return rlp.EncodeToBytes(c)
+}
+
+// DecodeCompactProof parses the output of CompactProof.Bytes
+func DecodeCompactProof(data []byte) (*CompactProof, error) {
+	var c CompactProof
+	if err := rlp.DecodeBytes(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid compact proof: %v", err)
+	}
+	// trie values are never empty, so an empty one can only be an absence proof
+	if len(c.Value) == 0 {
+		c.Value = nil
+	}
+	return &c, nil
+}
+
+// followedRefs walks key down nodes, and returns for each node but the last
+// the position of the reference to the next node in its RLP list. That reference
+// is always a direct child: nodes embedded in a step are too small to hold a hash.
+func followedRefs(nodes [][]byte, key []byte) ([]int, error) {
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	hexkey := keybytesToHex(key)
+	refs := make([]int, len(nodes)-1)
+	for i, raw := range nodes[:len(nodes)-1] {
+		n, err := decodeNode(nil, raw, 0)
+		if err != nil {
+			return nil, fmt.Errorf("step %d cannot decode raw node: %v", i, err)
+		}
+		switch t := n.(type) {
+		case *shortNode:
+			if hasTerm(t.Key) || len(hexkey) < len(t.Key) || !bytes.Equal(t.Key, hexkey[:len(t.Key)]) {
+				return nil, fmt.Errorf("step %d doesn't lead to another node for key %X", i, key)
+			}
+			hexkey = hexkey[len(t.Key):]
+			refs[i] = 1
+		case *fullNode:
+			if len(hexkey) == 0 || hexkey[0] == 16 {
+				return nil, fmt.Errorf("step %d doesn't lead to another node for key %X", i, key)
+			}
+			refs[i] = int(hexkey[0])
+			hexkey = hexkey[1:]
+		}
+	}
+	return refs, nil
+}
+
+// replaceRef swaps element pos of the RLP list raw, which must be from, for to
+func replaceRef(raw []byte, pos int, from, to []byte) ([]byte, error) {
+	items, err := listItems(raw)
+	if err != nil {
+		return nil, err
+	}
+	if pos >= len(items) {
+		return nil, fmt.Errorf("node has no element %d", pos)
+	}
+	if !bytes.Equal(items[pos], from) {
+		return nil, fmt.Errorf("unexpected reference %X at element %d", []byte(items[pos]), pos)
+	}
+	items[pos] = to
+	return rlp.EncodeToBytes(items)
+}
+
+// listItems splits the RLP list raw into the encoding of each of its elements
+func listItems(raw []byte) ([]rlp.RawValue, error) {
+	elems, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return nil, err
+	}
+	var items []rlp.RawValue
+	for len(elems) > 0 {
+		_, _, rest, err := rlp.Split(elems)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, rlp.RawValue(elems[:len(elems)-len(rest)]))
+		elems = rest
+	}
+	return items, nil
+}
+
+// hashRef is the RLP of a reference to the node with the given hash
+func hashRef(hash []byte) []byte {
+	return append([]byte{0xa0}, hash...)
+}
@@ -0,0 +1,113 @@
+package proof
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestCompactProof(t *testing.T) {
+	diskdb, tr, vals := diskTrie(t, 1000)
+	root := tr.Hash()
+
+	cases := map[string]struct {
+		key []byte
+	}{
+		"first value":  {key: vals[0].k},
+		"random value": {key: vals[len(vals)-3].k},
+		"absent key":   {key: randBytes(32)},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			proof, err := computeProofOrAbsence(diskdb, root, tc.key)
+			if err != nil {
+				t.Fatalf("computeProofOrAbsence: %+v", err)
+			}
+			compact, err := proof.Compact()
+			if err != nil {
+				t.Fatalf("Compact: %+v", err)
+			}
+
+			// every step but the last loses a 33 byte hash reference, for a 1 byte placeholder
+			var before, after int
+			for i, step := range proof.Steps {
+				before += len(step.Raw)
+				after += len(compact.Nodes[i])
+			}
+			if saved := 32 * (len(proof.Steps) - 1); before-after < saved {
+				t.Fatalf("Compacted %d bytes to %d, expected at least %d less", before, after, saved)
+			}
+
+			if err := VerifyCompactProof(compact, root); err != nil {
+				t.Fatalf("Invalid compact proof: %+v", err)
+			}
+			if err := VerifyCompactProof(compact, common.Hash{1}); err == nil {
+				t.Fatalf("Expected error for another root")
+			}
+
+			// it round trips through its encoding, and expands back to the original
+			encoded, err := compact.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes: %+v", err)
+			}
+			decoded, err := DecodeCompactProof(encoded)
+			if err != nil {
+				t.Fatalf("DecodeCompactProof: %+v", err)
+			}
+			expanded, err := decoded.Expand()
+			if err != nil {
+				t.Fatalf("Expand: %+v", err)
+			}
+			if !reflect.DeepEqual(proof, expanded) {
+				t.Fatalf("Expanded proof differs from the original")
+			}
+		})
+	}
+}
+
+func TestCompactProofTampered(t *testing.T) {
+	diskdb, tr, vals := diskTrie(t, 1000)
+	root := tr.Hash()
+	proof, err := ComputeProofFromDB(diskdb, root, vals[7].k)
+	if err != nil {
+		t.Fatalf("ComputeProofFromDB: %+v", err)
+	}
+	if len(proof.Steps) < 2 {
+		t.Fatalf("Expected a proof of several steps, got %d", len(proof.Steps))
+	}
+
+	// the standard nodes still hold the reference that should be elided
+	uncompacted := &CompactProof{Key: proof.Key, Value: proof.Value}
+	for _, step := range proof.Steps {
+		uncompacted.Nodes = append(uncompacted.Nodes, step.Raw)
+	}
+	if _, err := uncompacted.Expand(); err == nil {
+		t.Fatalf("Expected error expanding nodes with the hash left in")
+	}
+
+	cases := map[string]func(c *CompactProof){
+		"other value": func(c *CompactProof) { c.Value = []byte("foo") },
+		"other key":   func(c *CompactProof) { c.Key = vals[8].k },
+		"no last node": func(c *CompactProof) {
+			c.Nodes = c.Nodes[:len(c.Nodes)-1]
+		},
+		"changed last node": func(c *CompactProof) {
+			last := c.Nodes[len(c.Nodes)-1]
+			c.Nodes[len(c.Nodes)-1] = bytes.Replace(last, proof.Value, bytes.Repeat([]byte{0xff}, len(proof.Value)), 1)
+		},
+	}
+	for name, tamper := range cases {
+		t.Run(name, func(t *testing.T) {
+			compact, err := proof.Compact()
+			if err != nil {
+				t.Fatalf("Compact: %+v", err)
+			}
+			tamper(compact)
+			if err := VerifyCompactProof(compact, root); err == nil {
+				t.Fatalf("Expected error for tampered proof")
+			}
+		})
+	}
+}
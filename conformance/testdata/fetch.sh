@@ -0,0 +1,17 @@
+#!/bin/sh
+# Replaces the vectors here with the TrieTests files of ethereum/tests at the given
+# ref (default develop), unmodified, and records the commit they came from in UPSTREAM.
+set -e
+cd "$(dirname "$0")"
+
+ref=${1:-develop}
+commit=$(git ls-remote https://github.com/ethereum/tests "$ref" | cut -f1 | head -n1)
+case "$commit" in
+[0-9a-f]*) ;;
+*) commit=$ref ;;
+esac
+
+for file in trietest.json trieanyorder.json trietest_secureTrie.json trieanyorder_secureTrie.json hex_encoded_securetrie_test.json; do
+	curl -sSfL -o "$file" "https://raw.githubusercontent.com/ethereum/tests/$commit/TrieTests/$file"
+done
+echo "$commit" >UPSTREAM
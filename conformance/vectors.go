@@ -0,0 +1,214 @@
+// Package conformance checks proofs against the trie test vectors of the
+// Ethereum consensus tests (TrieTests in github.com/ethereum/tests).
+//
+// Every vector is a list of updates and the root they must lead to. Check builds
+// the trie, makes sure it has that root, then proves every key still in the trie
+// with ComputeProof, and the absence of every deleted key, and verifies them all.
+package conformance
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+
+	proof "github.com/confio/proofs-ethereum"
+)
+
+// Op sets Key to Value, or deletes it if Value is nil
+type Op struct {
+	Key   []byte
+	Value []byte
+}
+
+// Vector is one trie test
+type Vector struct {
+	Ops  []Op
+	Root common.Hash
+	// AnyOrder is set when the ops may be applied in any order, with the same root
+	AnyOrder bool
+}
+
+// ReadOrdered parses vectors in the format of trietest.json, where "in" is
+// a list of [key, value] pairs applied in order, a null value deleting the key
+func ReadOrdered(r io.Reader) (map[string]Vector, error) {
+	var raw map[string]struct {
+		In   [][]*string `json:"in"`
+		Root common.Hash `json:"root"`
+	}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	vectors := make(map[string]Vector, len(raw))
+	for name, test := range raw {
+		v := Vector{Root: test.Root}
+		for i, pair := range test.In {
+			if len(pair) != 2 || pair[0] == nil {
+				return nil, fmt.Errorf("%s: invalid pair %d", name, i)
+			}
+			op, err := parseOp(*pair[0], pair[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			v.Ops = append(v.Ops, op)
+		}
+		vectors[name] = v
+	}
+	return vectors, nil
+}
+
+// ReadAnyOrder parses vectors in the format of trieanyorder.json and
+// hex_encoded_securetrie_test.json, where "in" is an object of keys to values
+func ReadAnyOrder(r io.Reader) (map[string]Vector, error) {
+	var raw map[string]struct {
+		In   map[string]string `json:"in"`
+		Root common.Hash       `json:"root"`
+	}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	vectors := make(map[string]Vector, len(raw))
+	for name, test := range raw {
+		v := Vector{Root: test.Root, AnyOrder: true}
+		for key, value := range test.In {
+			value := value
+			op, err := parseOp(key, &value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			v.Ops = append(v.Ops, op)
+		}
+		// map order is random, start from a known one
+		sort.Slice(v.Ops, func(i, j int) bool { return bytes.Compare(v.Ops[i].Key, v.Ops[j].Key) < 0 })
+		vectors[name] = v
+	}
+	return vectors, nil
+}
+
+// parseOp decodes a key and value, which are hex if they start with 0x and
+// plain strings otherwise, like in the test files
+func parseOp(key string, value *string) (Op, error) {
+	k, err := parseString(key)
+	if err != nil {
+		return Op{}, err
+	}
+	if value == nil {
+		return Op{Key: k}, nil
+	}
+	v, err := parseString(*value)
+	if err != nil {
+		return Op{}, err
+	}
+	return Op{Key: k, Value: v}, nil
+}
+
+func parseString(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return []byte(s), nil
+	}
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex %q: %v", s, err)
+	}
+	return b, nil
+}
+
+// Build applies the ops to an empty trie, hashing keys first for a secure trie,
+// and commits it to the returned database. A nil or empty value deletes the key.
+func (v Vector) Build(secure bool) (*trie.Trie, ethdb.Database, error) {
+	diskdb := ethdb.NewMemDatabase()
+	triedb := trie.NewDatabase(diskdb)
+	tr, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, op := range v.Ops {
+		tr.Update(trieKey(op.Key, secure), op.Value)
+	}
+	root, err := tr.Commit(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		return nil, nil, err
+	}
+	return tr, diskdb, nil
+}
+
+// Check builds the trie of v, and proves and verifies every key in it, and the
+// absence of every key deleted from it. Vectors that may be applied in any order
+// are also built in reverse.
+func (v Vector) Check(secure bool) error {
+	if err := v.check(secure); err != nil {
+		return err
+	}
+	if !v.AnyOrder {
+		return nil
+	}
+	reversed := Vector{Ops: make([]Op, len(v.Ops)), Root: v.Root}
+	for i, op := range v.Ops {
+		reversed.Ops[len(v.Ops)-1-i] = op
+	}
+	if err := reversed.check(secure); err != nil {
+		return fmt.Errorf("in reverse order: %v", err)
+	}
+	return nil
+}
+
+func (v Vector) check(secure bool) error {
+	tr, diskdb, err := v.Build(secure)
+	if err != nil {
+		return err
+	}
+	if root := tr.Hash(); root != v.Root {
+		return fmt.Errorf("trie has root %X, expected %X", root, v.Root)
+	}
+
+	// the last op on each key decides if it is in the trie
+	final := make(map[string][]byte)
+	for _, op := range v.Ops {
+		final[string(op.Key)] = op.Value
+	}
+	for key, value := range final {
+		k := trieKey([]byte(key), secure)
+		if len(value) == 0 {
+			p, err := proof.ComputeAbsenceProofFromDB(diskdb, v.Root, k)
+			if err != nil {
+				return fmt.Errorf("absence of %X: %v", key, err)
+			}
+			if err := proof.VerifyAbsence(p, v.Root); err != nil {
+				return fmt.Errorf("absence of %X: %v", key, err)
+			}
+			continue
+		}
+
+		p, err := proof.ComputeProof(tr, k)
+		if err != nil {
+			return fmt.Errorf("key %X: %v", key, err)
+		}
+		if !bytes.Equal(p.Value, value) {
+			return fmt.Errorf("key %X: proof has value %X, expected %X", key, p.Value, value)
+		}
+		if err := proof.VerifyProofStrict(p, v.Root); err != nil {
+			return fmt.Errorf("key %X: %v", key, err)
+		}
+	}
+	return nil
+}
+
+func trieKey(key []byte, secure bool) []byte {
+	if secure {
+		return crypto.Keccak256(key)
+	}
+	return key
+}
@@ -0,0 +1,50 @@
+package conformance
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVectors(t *testing.T) {
+	cases := map[string]struct {
+		file   string
+		read   func(io.Reader) (map[string]Vector, error)
+		secure bool
+	}{
+		"trietest":            {file: "trietest.json", read: ReadOrdered},
+		"trieanyorder":        {file: "trieanyorder.json", read: ReadAnyOrder},
+		"trietest secure":     {file: "trietest_secureTrie.json", read: ReadOrdered, secure: true},
+		"trieanyorder secure": {file: "trieanyorder_secureTrie.json", read: ReadAnyOrder, secure: true},
+		"hex encoded secure":  {file: "hex_encoded_securetrie_test.json", read: ReadAnyOrder, secure: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			vectors := readVectors(t, tc.file, tc.read)
+			for vname, v := range vectors {
+				if err := v.Check(tc.secure); err != nil {
+					t.Errorf("%s: %+v", vname, err)
+				}
+			}
+		})
+	}
+}
+
+func readVectors(t *testing.T, file string, read func(io.Reader) (map[string]Vector, error)) map[string]Vector {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatalf("Open: %+v", err)
+	}
+	defer f.Close()
+	vectors, err := read(f)
+	if err != nil {
+		t.Fatalf("Reading %s: %+v", file, err)
+	}
+	if len(vectors) == 0 {
+		t.Fatalf("No vectors in %s", file)
+	}
+	return vectors
+}
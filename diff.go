@@ -0,0 +1,180 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ProofDiff compares two proofs of the same key under different roots, eg. of one
+// account in two blocks. Every change below the root changes the hash of each node
+// above it, so the proofs differ from the root down to the change, and are the same
+// again below it if the change was next to the path rather than on it.
+type ProofDiff struct {
+	Key              []byte
+	OldRoot, NewRoot common.Hash
+	// Divergence is the first level whose nodes differ, or -1 if the proofs are the same.
+	// It is 0 whenever the roots differ.
+	Divergence int
+	// Convergence is the first level from which both proofs have the same nodes to the
+	// end, or -1 if their last steps differ
+	Convergence int
+	// Levels compares the proofs step by step, from the root down. Where one proof is
+	// longer, its extra steps have no counterpart.
+	Levels []LevelDiff
+	// OldValue and NewValue are the values of the key, nil if it has none
+	OldValue, NewValue []byte
+}
+
+// ValueChanged tells if the key has a different value under the two roots
+func (d *ProofDiff) ValueChanged() bool {
+	return !bytes.Equal(d.OldValue, d.NewValue)
+}
+
+// LevelDiff compares the steps of two proofs at the same depth
+type LevelDiff struct {
+	// Old and New are the steps at this level, nil if that proof is shorter
+	Old, New *DiffNode
+	// Siblings are the children that changed when both steps are branch nodes,
+	// other than the ones the key follows
+	Siblings []SiblingDiff
+}
+
+// Changed tells if the steps at this level are different nodes
+func (l LevelDiff) Changed() bool {
+	return l.Old == nil || l.New == nil || !bytes.Equal(l.Old.Hash, l.New.Hash)
+}
+
+// DiffNode is one step of a proof, as it is compared
+type DiffNode struct {
+	Hash []byte
+	Kind NodeKind
+	// Path is the hex nibbles of the key that lead to this node
+	Path []byte
+	// Index is the child the key follows, if this is a branch node
+	Index int
+}
+
+// SiblingDiff is a child of a branch node that changed. Old and New are the RLP of the
+// child in each node: 0x80 if empty, 0xa0 and the hash for a node referenced by hash,
+// the node itself if embedded, and the value for slot 16.
+type SiblingDiff struct {
+	Index    int
+	Old, New []byte
+}
+
+// DiffProofs checks that a and b prove the same key, each against the root its first
+// step hashes to, and compares them level by level. Only the key, value and Raw encoding
+// of the steps are used, so either may be an absence proof, or have only Raw set.
+// A nil Value is taken from the steps, as eth_getProof doesn't return the raw value.
+func DiffProofs(a, b *Proof) (*ProofDiff, error) {
+	if !bytes.Equal(a.Key, b.Key) {
+		return nil, fmt.Errorf("proofs are for different keys %X and %X", a.Key, b.Key)
+	}
+	oldRoot, oldWalk, err := diffWalk(a)
+	if err != nil {
+		return nil, fmt.Errorf("old proof: %v", err)
+	}
+	newRoot, newWalk, err := diffWalk(b)
+	if err != nil {
+		return nil, fmt.Errorf("new proof: %v", err)
+	}
+
+	diff := &ProofDiff{
+		Key:         a.Key,
+		OldRoot:     oldRoot,
+		NewRoot:     newRoot,
+		Divergence:  -1,
+		Convergence: -1,
+		OldValue:    oldWalk.value,
+		NewValue:    newWalk.value,
+	}
+	oldNodes, newNodes := diffNodes(oldWalk.steps), diffNodes(newWalk.steps)
+	for i := 0; i < len(oldNodes) || i < len(newNodes); i++ {
+		var level LevelDiff
+		if i < len(oldNodes) {
+			level.Old = oldNodes[i]
+		}
+		if i < len(newNodes) {
+			level.New = newNodes[i]
+		}
+		if level.Old != nil && level.New != nil && level.Changed() {
+			level.Siblings, err = changedSiblings(oldWalk.steps[i], newWalk.steps[i])
+			if err != nil {
+				return nil, fmt.Errorf("step %d: %v", i, err)
+			}
+		}
+		diff.Levels = append(diff.Levels, level)
+	}
+
+	for i, level := range diff.Levels {
+		if level.Changed() {
+			if diff.Divergence < 0 {
+				diff.Divergence = i
+			}
+			diff.Convergence = -1
+		} else if diff.Convergence < 0 {
+			diff.Convergence = i
+		}
+	}
+	return diff, nil
+}
+
+// diffWalk checks proof against the hash of its first step, or the empty root if it has none
+func diffWalk(proof *Proof) (common.Hash, *walk, error) {
+	root := emptyRoot
+	if len(proof.Steps) > 0 {
+		root = common.BytesToHash(makeHashNode(proof.Steps[0].Raw))
+	}
+	w, err := walkPath(proof.Steps, root, proof.Key)
+	if err != nil {
+		return root, nil, err
+	}
+	if proof.Value != nil && !bytes.Equal(w.value, proof.Value) {
+		return root, nil, fmt.Errorf("proof value %X doesn't match %X stored in the trie", proof.Value, w.value)
+	}
+	return root, w, nil
+}
+
+// diffNodes describes the steps walkPath derived, with the path leading to each
+func diffNodes(steps []Step) []*DiffNode {
+	nodes := make([]*DiffNode, len(steps))
+	var path []byte
+	for i, step := range steps {
+		nodes[i] = &DiffNode{Hash: step.Hash, Kind: kindOf(step.Step), Path: path}
+		switch n := step.Step.(type) {
+		case *shortNode:
+			path = concat(path, n.Key...)
+		case *fullNode:
+			nodes[i].Index = step.Index
+			path = concat(path, byte(step.Index))
+		}
+	}
+	return nodes
+}
+
+// changedSiblings compares the children of two branch nodes, skipping the ones the key follows
+func changedSiblings(a, b Step) ([]SiblingDiff, error) {
+	_, aFull := a.Step.(*fullNode)
+	_, bFull := b.Step.(*fullNode)
+	if !aFull || !bFull {
+		return nil, nil
+	}
+	aItems, err := listItems(a.Raw)
+	if err != nil {
+		return nil, err
+	}
+	bItems, err := listItems(b.Raw)
+	if err != nil {
+		return nil, err
+	}
+	var siblings []SiblingDiff
+	for i := range aItems {
+		if i == a.Index || i == b.Index || bytes.Equal(aItems[i], bItems[i]) {
+			continue
+		}
+		siblings = append(siblings, SiblingDiff{Index: i, Old: aItems[i], New: bItems[i]})
+	}
+	return siblings, nil
+}
@@ -0,0 +1,108 @@
+package proof
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestDiffProofs(t *testing.T) {
+	_, tr, vals := diskTrie(t, 200)
+	key := vals[0].k
+	prove := func(key []byte) *Proof {
+		p, err := ComputeProof(tr, key)
+		if err != nil {
+			t.Fatalf("ComputeProof: %+v", err)
+		}
+		return p
+	}
+	before := prove(key)
+
+	same, err := DiffProofs(before, prove(key))
+	if err != nil {
+		t.Fatalf("DiffProofs: %+v", err)
+	}
+	if same.Divergence != -1 || same.Convergence != 0 || same.ValueChanged() || same.OldRoot != same.NewRoot {
+		t.Fatalf("Unexpected diff of a proof with itself: %+v", same)
+	}
+
+	// a key under the same first branch as key
+	var other kv
+	for _, v := range vals[1:] {
+		if v.k[0]>>4 == key[0]>>4 {
+			other = v
+			break
+		}
+	}
+	if other.k == nil {
+		t.Fatalf("No key next to %X", key)
+	}
+	tr.Update(other.k, randBytes(20))
+	sibling, err := DiffProofs(before, prove(key))
+	if err != nil {
+		t.Fatalf("DiffProofs: %+v", err)
+	}
+	// both keys are under the root and the same branch below it, the leaf is untouched
+	if sibling.Divergence != 0 || sibling.Convergence < 2 || sibling.Convergence >= len(sibling.Levels) {
+		t.Fatalf("Expected the proofs to converge below the second level, got %d to %d of %d levels",
+			sibling.Divergence, sibling.Convergence, len(sibling.Levels))
+	}
+	if sibling.ValueChanged() {
+		t.Fatalf("Value changed from %X to %X", sibling.OldValue, sibling.NewValue)
+	}
+	for i, level := range sibling.Levels[:sibling.Convergence] {
+		if !level.Changed() {
+			t.Fatalf("Level %d didn't change", i)
+		}
+	}
+	// the deepest changed node is where the two keys part
+	parting := sibling.Levels[sibling.Convergence-1]
+	nibble := int(keybytesToHex(other.k)[len(parting.Old.Path)])
+	if len(parting.Siblings) != 1 || parting.Siblings[0].Index != nibble {
+		t.Fatalf("Expected sibling %d to change, got %+v", nibble, parting.Siblings)
+	}
+
+	// changing the value changes every level
+	mid := prove(key)
+	tr.Update(key, []byte("new value"))
+	value, err := DiffProofs(mid, prove(key))
+	if err != nil {
+		t.Fatalf("DiffProofs: %+v", err)
+	}
+	if value.Divergence != 0 || value.Convergence != -1 || !value.ValueChanged() || !bytes.Equal(value.NewValue, []byte("new value")) {
+		t.Fatalf("Unexpected diff of a changed value: %+v", value)
+	}
+	for i, level := range value.Levels {
+		if len(level.Siblings) != 0 {
+			t.Fatalf("Level %d has changed siblings %+v", i, level.Siblings)
+		}
+	}
+
+	// deleting the key compares with an absence proof
+	tr.Delete(key)
+	proofdb := ethdb.NewMemDatabase()
+	if err := tr.Prove(key, 0, proofdb); err != nil {
+		t.Fatalf("Prove: %+v", err)
+	}
+	absent, err := ComputeAbsenceProofFromDB(proofdb, tr.Hash(), key)
+	if err != nil {
+		t.Fatalf("ComputeAbsenceProofFromDB: %+v", err)
+	}
+	deleted, err := DiffProofs(before, absent)
+	if err != nil {
+		t.Fatalf("DiffProofs: %+v", err)
+	}
+	if deleted.NewValue != nil || !deleted.ValueChanged() {
+		t.Fatalf("Expected the value to be gone, got %X", deleted.NewValue)
+	}
+
+	if _, err := DiffProofs(before, prove(other.k)); err == nil {
+		t.Fatalf("Expected error for proofs of different keys")
+	}
+	wrong := *before
+	wrong.Value = []byte("foo")
+	if _, err := DiffProofs(&wrong, before); err == nil {
+		t.Fatalf("Expected error for a proof of the wrong value")
+	}
+}
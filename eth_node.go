@@ -17,6 +17,7 @@
 package proof
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"strings"
@@ -235,3 +236,34 @@ func wrapError(err error, ctx string) error {
 func (err *decodeError) Error() string {
 	return fmt.Sprintf("%v (decode path: %s)", err.what, strings.Join(err.stack, "<-"))
 }
+
+// sameNode returns whether a and b describe the same trie node, ignoring
+// any cached flags.
+func sameNode(a, b node) bool {
+	switch an := a.(type) {
+	case nil:
+		return b == nil
+	case *fullNode:
+		bn, ok := b.(*fullNode)
+		if !ok {
+			return false
+		}
+		for i := range &an.Children {
+			if !sameNode(an.Children[i], bn.Children[i]) {
+				return false
+			}
+		}
+		return true
+	case *shortNode:
+		bn, ok := b.(*shortNode)
+		return ok && bytes.Equal(an.Key, bn.Key) && sameNode(an.Val, bn.Val)
+	case hashNode:
+		bn, ok := b.(hashNode)
+		return ok && bytes.Equal(an, bn)
+	case valueNode:
+		bn, ok := b.(valueNode)
+		return ok && bytes.Equal(an, bn)
+	default:
+		return false
+	}
+}
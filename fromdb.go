@@ -0,0 +1,116 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// emptyRoot is the root hash of an empty trie, which is never stored in a database
+var emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// NodeReader looks up the RLP encoding of trie nodes by their hash.
+// Any ethdb.Database satisfies it.
+type NodeReader interface {
+	Get(key []byte) ([]byte, error)
+}
+
+var _ NodeReader = (ethdb.Database)(nil)
+
+// NodeMap is a NodeReader over an in-memory set of nodes
+type NodeMap map[common.Hash][]byte
+
+var _ NodeReader = NodeMap(nil)
+
+func (m NodeMap) Get(key []byte) ([]byte, error) {
+	if len(key) != hashLen {
+		return nil, fmt.Errorf("invalid node hash %X", key)
+	}
+	raw, ok := m[common.BytesToHash(key)]
+	if !ok {
+		return nil, fmt.Errorf("missing trie node %X", key)
+	}
+	return raw, nil
+}
+
+// ComputeProofFromDB returns the proof value for a key in the trie with the given root,
+// reading nodes by hash straight from db rather than going through a trie.Trie.
+func ComputeProofFromDB(db NodeReader, root common.Hash, key []byte) (*Proof, error) {
+	load := func(hash hashNode) (Step, error) { return readStep(db, hash) }
+	proof, _, err := proveFromDB(load, root, key)
+	if err != nil {
+		return nil, err
+	}
+	if proof == nil {
+		return nil, fmt.Errorf("No value found for key %X", key)
+	}
+	return proof, nil
+}
+
+// proveFromDB walks from root towards key, loading every hashed node on the way.
+// If there is no value for key, it returns a nil proof (and no error), along with
+// the path that shows it.
+func proveFromDB(load func(hashNode) (Step, error), root common.Hash, key []byte) (*Proof, []Step, error) {
+	if root == emptyRoot {
+		return nil, nil, nil
+	}
+	hexkey := keybytesToHex(key)
+	var path []Step
+
+	var cur node = hashNode(root[:])
+	for {
+		switch n := cur.(type) {
+		case hashNode:
+			step, err := load(n)
+			if err != nil {
+				return nil, nil, err
+			}
+			path = append(path, step)
+			cur = step.Step
+		case *shortNode:
+			if len(hexkey) < len(n.Key) || !bytes.Equal(n.Key, hexkey[:len(n.Key)]) {
+				return nil, path, nil
+			}
+			hexkey = hexkey[len(n.Key):]
+			cur = n.Val
+		case *fullNode:
+			if len(hexkey) == 0 {
+				return nil, path, nil
+			}
+			cur = n.Children[hexkey[0]]
+			hexkey = hexkey[1:]
+		case valueNode:
+			proof, err := buildProof(key, n, path)
+			return proof, path, err
+		case nil:
+			return nil, path, nil
+		default:
+			return nil, nil, fmt.Errorf("Unknown type: %T", cur)
+		}
+	}
+}
+
+// readStep loads the node with the given hash from db, making sure the
+// stored bytes really hash to it
+func readStep(db NodeReader, hash hashNode) (Step, error) {
+	raw, err := db.Get(hash)
+	if err != nil {
+		return Step{}, fmt.Errorf("cannot read node %X: %v", []byte(hash), err)
+	}
+	if len(raw) == 0 {
+		return Step{}, fmt.Errorf("missing trie node %X", []byte(hash))
+	}
+	if got := makeHashNode(raw); !bytes.Equal(got, hash) {
+		return Step{}, fmt.Errorf("node stored under %X hashes to %X", []byte(hash), got)
+	}
+
+	hash = append(hashNode{}, hash...)
+	raw = append([]byte{}, raw...)
+	n, err := decodeNode(hash, raw, 0)
+	if err != nil {
+		return Step{}, err
+	}
+	return Step{Step: n, Hash: hash, Raw: raw}, nil
+}
@@ -0,0 +1,99 @@
+package proof
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestComputeProofFromDB(t *testing.T) {
+	diskdb, tr, vals := diskTrie(t, 1000)
+	root := tr.Hash()
+
+	for _, query := range []kv{vals[0], vals[150], vals[len(vals)-3]} {
+		proof, err := ComputeProofFromDB(diskdb, root, query.k)
+		if err != nil {
+			t.Fatalf("ComputeProofFromDB: %+v", err)
+		}
+		if !bytes.Equal(query.v, proof.Value) {
+			t.Fatalf("invalid value: %X (expected %X)", proof.Value, query.v)
+		}
+		if err := VerifyProof(proof, root); err != nil {
+			t.Fatalf("Invalid proof %+v", err)
+		}
+
+		// should be exactly what we get from the trie itself
+		expected, err := ComputeProof(tr, query.k)
+		if err != nil {
+			t.Fatalf("ComputeProof: %+v", err)
+		}
+		if len(proof.Steps) != len(expected.Steps) {
+			t.Fatalf("Got %d steps, expected %d", len(proof.Steps), len(expected.Steps))
+		}
+		for i, step := range proof.Steps {
+			want := expected.Steps[i]
+			if !bytes.Equal(want.Raw, step.Raw) || !bytes.Equal(want.Hash, step.Hash) || want.Index != step.Index {
+				t.Fatalf("Step %d differs from ComputeProof", i)
+			}
+		}
+		if !bytes.Equal(expected.HexRemainder, proof.HexRemainder) {
+			t.Fatalf("HexRemainder %X, expected %X", proof.HexRemainder, expected.HexRemainder)
+		}
+
+		// a fixture with only the nodes on the path is enough to prove it again
+		nodes := NodeMap{}
+		for _, step := range proof.Steps {
+			nodes[common.BytesToHash(step.Hash)] = step.Raw
+		}
+		again, err := ComputeProofFromDB(nodes, root, query.k)
+		if err != nil {
+			t.Fatalf("ComputeProofFromDB on fixture: %+v", err)
+		}
+		if err := VerifyProof(again, root); err != nil {
+			t.Fatalf("Invalid proof from fixture %+v", err)
+		}
+	}
+
+	// missing key
+	if _, err := ComputeProofFromDB(diskdb, root, randBytes(32)); err == nil {
+		t.Fatalf("Expected error for missing key")
+	}
+	// unknown root
+	if _, err := ComputeProofFromDB(diskdb, common.BytesToHash(randBytes(32)), vals[0].k); err == nil {
+		t.Fatalf("Expected error for unknown root")
+	}
+	// node stored under the wrong hash
+	bad := NodeMap{root: []byte{0xc2, 0x80, 0x80}}
+	if _, err := ComputeProofFromDB(bad, root, vals[0].k); err == nil {
+		t.Fatalf("Expected error for node not matching its hash")
+	}
+}
+
+// diskTrie is like randomTrie, but also flushes all nodes to the returned database
+func diskTrie(t *testing.T, n int) (ethdb.Database, *trie.Trie, []kv) {
+	diskdb := ethdb.NewMemDatabase()
+	triedb := trie.NewDatabase(diskdb)
+	tr, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		t.Fatalf("cannot create an empty trie: %s", err)
+	}
+
+	var vals []kv
+	for i := 0; i < n; i++ {
+		value := kv{k: randBytes(32), v: randBytes(20)}
+		tr.Update(value.k, value.v)
+		vals = append(vals, value)
+	}
+
+	root, err := tr.Commit(nil)
+	if err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("cannot flush: %s", err)
+	}
+	return diskdb, tr, vals
+}
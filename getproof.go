@@ -0,0 +1,47 @@
+package proof
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// GetProofResult is the part of an eth_getProof result holding the proofs
+type GetProofResult struct {
+	Address      common.Address       `json:"address"`
+	AccountProof []hexutil.Bytes      `json:"accountProof"`
+	StorageProof []StorageProofResult `json:"storageProof"`
+}
+
+// StorageProofResult is the proof of one slot in an eth_getProof result
+type StorageProofResult struct {
+	Key   string          `json:"key"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// ReadGetProofResult parses an eth_getProof response. It accepts either the whole
+// JSON-RPC response or just its result.
+func ReadGetProofResult(in io.Reader) (*GetProofResult, error) {
+	var msg struct {
+		Result *GetProofResult `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		GetProofResult
+	}
+	if err := json.NewDecoder(in).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("cannot parse eth_getProof response: %v", err)
+	}
+	switch {
+	case msg.Error != nil:
+		return nil, fmt.Errorf("eth_getProof failed: %s", msg.Error.Message)
+	case msg.Result != nil:
+		return msg.Result, nil
+	case len(msg.AccountProof) == 0:
+		return nil, fmt.Errorf("no account proof in input")
+	}
+	return &msg.GetProofResult, nil
+}
@@ -0,0 +1,46 @@
+package proof
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestReadGetProofResult(t *testing.T) {
+	result := `{"address":"0x00000000000000000000000000000000deadbeef","accountProof":["0xc2808001"],` +
+		`"storageProof":[{"key":"0x1","value":"0x2","proof":["0xc3808002"]}]}`
+
+	cases := map[string]struct {
+		input string
+		isErr bool
+	}{
+		"response":      {input: `{"jsonrpc":"2.0","id":1,"result":` + result + `}`},
+		"result only":   {input: result},
+		"failed call":   {input: `{"jsonrpc":"2.0","id":1,"error":{"message":"header not found"}}`, isErr: true},
+		"no proof":      {input: `{"jsonrpc":"2.0","id":1}`, isErr: true},
+		"invalid json":  {input: `{"result":`, isErr: true},
+		"invalid bytes": {input: `{"accountProof":["0xc"]}`, isErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ReadGetProofResult(strings.NewReader(tc.input))
+			if tc.isErr {
+				if err == nil {
+					t.Fatalf("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadGetProofResult: %+v", err)
+			}
+			if got.Address != common.HexToAddress("0xdeadbeef") || len(got.AccountProof) != 1 || got.AccountProof[0][3] != 1 {
+				t.Fatalf("Unexpected account proof %+v", got)
+			}
+			if len(got.StorageProof) != 1 || got.StorageProof[0].Key != "0x1" || got.StorageProof[0].Proof[0][3] != 2 {
+				t.Fatalf("Unexpected storage proof %+v", got.StorageProof)
+			}
+		})
+	}
+}
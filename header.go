@@ -0,0 +1,160 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// legacyHeaderFields is the number of fields every header has, up to Nonce
+const legacyHeaderFields = 15
+
+// Header is a block header with the fields added by every fork since London.
+// types.Header of the go-ethereum version we build against stops at Nonce, so it
+// can't decode current headers, and computes the wrong hash for them.
+//
+// The fork fields are nil on headers from before the fork that added them. They
+// are appended in order, so a header can't have one set and an earlier one nil.
+type Header struct {
+	ParentHash  common.Hash
+	UncleHash   common.Hash
+	Coinbase    common.Address
+	Root        common.Hash
+	TxHash      common.Hash
+	ReceiptHash common.Hash
+	Bloom       types.Bloom
+	Difficulty  *big.Int
+	Number      *big.Int
+	GasLimit    uint64
+	GasUsed     uint64
+	Time        uint64
+	Extra       []byte
+	MixDigest   common.Hash
+	Nonce       types.BlockNonce
+
+	// BaseFee was added by London (EIP-1559)
+	BaseFee *big.Int
+	// WithdrawalsRoot was added by Shanghai (EIP-4895)
+	WithdrawalsRoot *common.Hash
+	// BlobGasUsed, ExcessBlobGas (EIP-4844) and ParentBeaconRoot (EIP-4788) were added by Cancun
+	BlobGasUsed      *uint64
+	ExcessBlobGas    *uint64
+	ParentBeaconRoot *common.Hash
+	// RequestsHash was added by Prague (EIP-7685)
+	RequestsHash *common.Hash
+}
+
+// DecodeHeader parses the RLP of a header from any fork, rejecting anything but its
+// canonical encoding, as the block hash is taken over these bytes
+func DecodeHeader(raw []byte) (*Header, error) {
+	var items []rlp.RawValue
+	if err := rlp.DecodeBytes(raw, &items); err != nil {
+		return nil, fmt.Errorf("invalid header encoding: %v", err)
+	}
+	var h Header
+	targets := h.fields()
+	if len(items) < legacyHeaderFields || len(items) > len(targets) {
+		return nil, fmt.Errorf("header has %d fields, expected %d to %d", len(items), legacyHeaderFields, len(targets))
+	}
+	for i, item := range items {
+		if err := rlp.DecodeBytes(item, targets[i]); err != nil {
+			return nil, fmt.Errorf("invalid header field %d: %v", i, err)
+		}
+	}
+
+	// the decoder already rejects most non-canonical input, re-encoding catches the rest
+	canonical, err := rlp.EncodeToBytes(&h)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(canonical, raw) {
+		return nil, fmt.Errorf("non-canonical header encoding")
+	}
+	return &h, nil
+}
+
+// fields points to every field, in encoding order
+func (h *Header) fields() []interface{} {
+	return []interface{}{
+		&h.ParentHash, &h.UncleHash, &h.Coinbase, &h.Root, &h.TxHash, &h.ReceiptHash, &h.Bloom,
+		&h.Difficulty, &h.Number, &h.GasLimit, &h.GasUsed, &h.Time, &h.Extra, &h.MixDigest, &h.Nonce,
+		&h.BaseFee, &h.WithdrawalsRoot, &h.BlobGasUsed, &h.ExcessBlobGas, &h.ParentBeaconRoot, &h.RequestsHash,
+	}
+}
+
+// EncodeRLP writes the fields up to the last fork field that is set
+func (h *Header) EncodeRLP(w io.Writer) error {
+	set := []bool{
+		h.BaseFee != nil, h.WithdrawalsRoot != nil, h.BlobGasUsed != nil,
+		h.ExcessBlobGas != nil, h.ParentBeaconRoot != nil, h.RequestsHash != nil,
+	}
+	n := legacyHeaderFields
+	for i, ok := range set {
+		if ok {
+			n = legacyHeaderFields + i + 1
+		}
+	}
+	for i, ok := range set[:n-legacyHeaderFields] {
+		if !ok {
+			return fmt.Errorf("header field %d is missing, but later fork fields are set", legacyHeaderFields+i)
+		}
+	}
+	return rlp.Encode(w, h.fields()[:n])
+}
+
+// Hash is the block hash, the keccak256 of the RLP encoding of the header
+func (h *Header) Hash() (common.Hash, error) {
+	raw, err := rlp.EncodeToBytes(h)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(raw), nil
+}
+
+// Withdrawal is a validator withdrawal from the beacon chain, as stored in the
+// withdrawals trie of a block since Shanghai (EIP-4895)
+type Withdrawal struct {
+	Index     uint64
+	Validator uint64
+	Address   common.Address
+	// Amount is in Gwei
+	Amount uint64
+}
+
+// VerifyWithdrawal checks that proof holds the withdrawal at the given position in
+// the block of header, in its withdrawals trie, and returns it
+func VerifyWithdrawal(proof *Proof, header *Header, position uint) (*Withdrawal, error) {
+	if header.WithdrawalsRoot == nil {
+		return nil, fmt.Errorf("header %v is from before Shanghai and has no withdrawals", header.Number)
+	}
+	// like the transaction and receipt tries, the withdrawals trie is keyed by the rlp of the position
+	key, err := rlp.EncodeToBytes(position)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(proof.Key, key) {
+		return nil, fmt.Errorf("proof is not for withdrawal %d", position)
+	}
+	if err := VerifyProof(proof, *header.WithdrawalsRoot); err != nil {
+		return nil, err
+	}
+
+	var withdrawal Withdrawal
+	if err := rlp.DecodeBytes(proof.Value, &withdrawal); err != nil {
+		return nil, fmt.Errorf("invalid withdrawal encoding: %v", err)
+	}
+	canonical, err := rlp.EncodeToBytes(&withdrawal)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(canonical, proof.Value) {
+		return nil, fmt.Errorf("non-canonical withdrawal encoding")
+	}
+	return &withdrawal, nil
+}
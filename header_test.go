@@ -0,0 +1,173 @@
+package proof
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestDecodeLegacyHeader(t *testing.T) {
+	// the mainnet genesis block
+	genesis := &types.Header{
+		UncleHash:   types.EmptyUncleHash,
+		Root:        common.HexToHash("0xd7f8974fb5ac78d9ac099b9ad5018bedc2ce0a72dad1827a1709da30580f0544"),
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+		Difficulty:  big.NewInt(0x400000000),
+		Number:      big.NewInt(0),
+		GasLimit:    5000,
+		Extra:       hexutil.MustDecode("0x11bbe8db4e347b4e8c937c1c8370e4b5ed33adb3db69cbdb7a38e1e50b1b82fa"),
+		Nonce:       types.EncodeNonce(0x42),
+	}
+	raw, err := rlp.EncodeToBytes(genesis)
+	if err != nil {
+		t.Fatalf("EncodeToBytes: %+v", err)
+	}
+
+	header, err := DecodeHeader(raw)
+	if err != nil {
+		t.Fatalf("DecodeHeader: %+v", err)
+	}
+	hash, err := header.Hash()
+	if err != nil {
+		t.Fatalf("Hash: %+v", err)
+	}
+	if hash != common.HexToHash("0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3") || hash != genesis.Hash() {
+		t.Fatalf("Got hash %X for the genesis block", hash)
+	}
+	if header.BaseFee != nil || header.WithdrawalsRoot != nil || header.RequestsHash != nil {
+		t.Fatalf("Legacy header has fork fields set")
+	}
+}
+
+func TestDecodeHeader(t *testing.T) {
+	hash := func(b byte) *common.Hash { h := common.Hash{b}; return &h }
+	gas := func(n uint64) *uint64 { return &n }
+	legacy := func() *Header {
+		return &Header{
+			ParentHash: common.Hash{1}, UncleHash: types.EmptyUncleHash, Coinbase: common.Address{2},
+			Root: common.Hash{3}, TxHash: types.EmptyRootHash, ReceiptHash: types.EmptyRootHash,
+			Difficulty: big.NewInt(0), Number: big.NewInt(21000000), GasLimit: 36000000, GasUsed: 12000000,
+			Time: 1730000000, Extra: []byte("builder"), MixDigest: common.Hash{4},
+		}
+	}
+	london := legacy()
+	london.BaseFee = big.NewInt(7)
+	shanghai := *london
+	shanghai.WithdrawalsRoot = hash(5)
+	cancun := shanghai
+	cancun.BlobGasUsed, cancun.ExcessBlobGas, cancun.ParentBeaconRoot = gas(131072), gas(0), hash(6)
+	prague := cancun
+	prague.RequestsHash = hash(7)
+
+	for name, h := range map[string]*Header{"legacy": legacy(), "london": london, "shanghai": &shanghai, "cancun": &cancun, "prague": &prague} {
+		t.Run(name, func(t *testing.T) {
+			raw, err := rlp.EncodeToBytes(h)
+			if err != nil {
+				t.Fatalf("EncodeToBytes: %+v", err)
+			}
+			decoded, err := DecodeHeader(raw)
+			if err != nil {
+				t.Fatalf("DecodeHeader: %+v", err)
+			}
+			want, _ := h.Hash()
+			got, err := decoded.Hash()
+			if err != nil || got != want {
+				t.Fatalf("Got hash %X (%v), expected %X", got, err, want)
+			}
+			if (h.BaseFee == nil) != (decoded.BaseFee == nil) || (h.RequestsHash == nil) != (decoded.RequestsHash == nil) {
+				t.Fatalf("Fork fields changed: %+v", decoded)
+			}
+		})
+	}
+
+	// the withdrawals root is part of the hash
+	a, _ := shanghai.Hash()
+	b, _ := london.Hash()
+	if a == b {
+		t.Fatalf("Withdrawals root doesn't change the hash")
+	}
+
+	// a gap in the fork fields can't be encoded
+	gap := *london
+	gap.ParentBeaconRoot = hash(6)
+	if _, err := gap.Hash(); err == nil {
+		t.Fatalf("Expected error for a header with a gap in its fork fields")
+	}
+
+	rawPrague, _ := rlp.EncodeToBytes(&prague)
+	var items []rlp.RawValue
+	if err := rlp.DecodeBytes(rawPrague, &items); err != nil {
+		t.Fatalf("DecodeBytes: %+v", err)
+	}
+	invalid := map[string][]rlp.RawValue{
+		"too few fields":  items[:14],
+		"too many fields": append(append([]rlp.RawValue{}, items...), rlp.RawValue{0x80}),
+		// base fee 7 with a leading zero
+		"non-canonical field": append(append(append([]rlp.RawValue{}, items[:15]...), rlp.RawValue{0x82, 0x00, 0x07}), items[16:]...),
+		"short hash":          append(append([]rlp.RawValue{}, items[:16]...), rlp.RawValue{0x81, 0x05}),
+	}
+	for name, fields := range invalid {
+		t.Run(name, func(t *testing.T) {
+			raw, err := rlp.EncodeToBytes(fields)
+			if err != nil {
+				t.Fatalf("EncodeToBytes: %+v", err)
+			}
+			if _, err := DecodeHeader(raw); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+	}
+	if _, err := DecodeHeader(append(rawPrague, 0x00)); err == nil {
+		t.Fatalf("Expected error for trailing data")
+	}
+}
+
+func TestVerifyWithdrawal(t *testing.T) {
+	tr, err := trie.New(common.Hash{}, trie.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("trie.New: %+v", err)
+	}
+	var withdrawals []Withdrawal
+	for i := uint64(0); i < 16; i++ {
+		w := Withdrawal{Index: 1000 + i, Validator: 500000 + i, Address: common.BigToAddress(big.NewInt(int64(i))), Amount: 18000000 + i}
+		key, _ := rlp.EncodeToBytes(uint(i))
+		tr.Update(key, mustEncode(t, &w))
+		withdrawals = append(withdrawals, w)
+	}
+	root := tr.Hash()
+	header := &Header{Difficulty: big.NewInt(0), Number: big.NewInt(17034870), BaseFee: big.NewInt(1), WithdrawalsRoot: &root}
+
+	key, _ := rlp.EncodeToBytes(uint(9))
+	proof, err := ComputeProof(tr, key)
+	if err != nil {
+		t.Fatalf("ComputeProof: %+v", err)
+	}
+	got, err := VerifyWithdrawal(proof, header, 9)
+	if err != nil {
+		t.Fatalf("VerifyWithdrawal: %+v", err)
+	}
+	if *got != withdrawals[9] {
+		t.Fatalf("Got withdrawal %+v, expected %+v", got, withdrawals[9])
+	}
+
+	if _, err := VerifyWithdrawal(proof, header, 8); err == nil {
+		t.Fatalf("Expected error for another position")
+	}
+	london := *header
+	london.WithdrawalsRoot = nil
+	if _, err := VerifyWithdrawal(proof, &london, 9); err == nil {
+		t.Fatalf("Expected error for a header without withdrawals")
+	}
+	other := common.Hash{1}
+	london.WithdrawalsRoot = &other
+	if _, err := VerifyWithdrawal(proof, &london, 9); err == nil {
+		t.Fatalf("Expected error for another withdrawals root")
+	}
+}
@@ -0,0 +1,82 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// HintMismatch is a field of a proof that disagrees with what the verifier
+// derived from the key and the raw nodes
+type HintMismatch struct {
+	// Step is the index of the step, or -1 for Proof.HexRemainder
+	Step int
+	// Field is "Hash", "Index", "Step" or "HexRemainder"
+	Field string
+	// Supplied is what the proof has, Derived what it should have
+	Supplied string
+	Derived  string
+}
+
+func (m HintMismatch) String() string {
+	if m.Step < 0 {
+		return fmt.Sprintf("%s is %s, derived %s", m.Field, m.Supplied, m.Derived)
+	}
+	return fmt.Sprintf("step %d %s is %s, derived %s", m.Step, m.Field, m.Supplied, m.Derived)
+}
+
+// VerifyProofHints checks proof using only its Key, Value and the Raw encoding of its
+// steps. The index followed in every full node comes from the key, and the hash
+// expected of every step from its parent, so Step.Hash, Step.Index, Step.Step and
+// HexRemainder are untrusted hints. A proof with only Raw set is valid.
+//
+// The error says whether the proof is valid. If it is, the hints that disagree with the
+// derived values are returned, which is not an error but points to a confused or
+// malicious producer. A nil Step.Hash or Step.Step is taken as not supplied, as is
+// Step.Index on a step without Step.Step (and an Index of 0).
+// Proofs with a nil Value are checked as absence proofs.
+func VerifyProofHints(proof *Proof, rootHash common.Hash) ([]HintMismatch, error) {
+	w, err := walkPath(proof.Steps, rootHash, proof.Key)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case proof.Value == nil && w.value != nil:
+		return nil, fmt.Errorf("key %X has a value", proof.Key)
+	case !bytes.Equal(proof.Value, w.value):
+		return nil, fmt.Errorf("proof value %X doesn't match %X stored in the trie", proof.Value, w.value)
+	}
+
+	var mismatches []HintMismatch
+	for i, step := range proof.Steps {
+		derived := w.steps[i]
+		if step.Hash != nil && !bytes.Equal(step.Hash, derived.Hash) {
+			mismatches = append(mismatches, HintMismatch{
+				Step: i, Field: "Hash",
+				Supplied: fmt.Sprintf("%X", step.Hash), Derived: fmt.Sprintf("%X", derived.Hash),
+			})
+		}
+		if step.Step != nil && !sameNode(step.Step, derived.Step) {
+			mismatches = append(mismatches, HintMismatch{
+				Step: i, Field: "Step",
+				Supplied: fmt.Sprint(step.Step), Derived: fmt.Sprint(derived.Step),
+			})
+		}
+		_, isFull := derived.Step.(*fullNode)
+		indexSupplied := step.Step != nil || step.Index != 0
+		if indexSupplied && ((isFull && step.Index != derived.Index) || (!isFull && step.Index != 0)) {
+			mismatches = append(mismatches, HintMismatch{
+				Step: i, Field: "Index",
+				Supplied: fmt.Sprint(step.Index), Derived: fmt.Sprint(derived.Index),
+			})
+		}
+	}
+	if proof.HexRemainder != nil && !bytes.Equal(proof.HexRemainder, w.remainder) {
+		mismatches = append(mismatches, HintMismatch{
+			Step: -1, Field: "HexRemainder",
+			Supplied: fmt.Sprintf("%X", proof.HexRemainder), Derived: fmt.Sprintf("%X", w.remainder),
+		})
+	}
+	return mismatches, nil
+}
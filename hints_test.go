@@ -0,0 +1,101 @@
+package proof
+
+import (
+	"testing"
+)
+
+func TestVerifyProofHints(t *testing.T) {
+	diskdb, tr, vals := diskTrie(t, 1000)
+	root := tr.Hash()
+	present, err := ComputeProofFromDB(diskdb, root, vals[5].k)
+	if err != nil {
+		t.Fatalf("ComputeProofFromDB: %+v", err)
+	}
+	absent, err := ComputeAbsenceProofFromDB(diskdb, root, randBytes(32))
+	if err != nil {
+		t.Fatalf("ComputeAbsenceProofFromDB: %+v", err)
+	}
+
+	cases := map[string]struct {
+		proof  *Proof
+		tamper func(p *Proof)
+		fields []string
+		isErr  bool
+	}{
+		"as computed": {proof: present},
+		"absence":     {proof: absent},
+		"raw only": {
+			proof: present,
+			tamper: func(p *Proof) {
+				for i := range p.Steps {
+					p.Steps[i] = Step{Raw: p.Steps[i].Raw}
+				}
+				p.HexRemainder = nil
+			},
+		},
+		"wrong hash": {
+			proof:  present,
+			tamper: func(p *Proof) { p.Steps[1].Hash = p.Steps[0].Hash },
+			fields: []string{"Hash"},
+		},
+		"wrong index": {
+			proof: present,
+			// the root is a full node in a trie this size
+			tamper: func(p *Proof) { p.Steps[0].Index = (p.Steps[0].Index + 1) % 16 },
+			fields: []string{"Index"},
+		},
+		"wrong node": {
+			proof:  absent,
+			tamper: func(p *Proof) { p.Steps[0].Step = p.Steps[1].Step },
+			fields: []string{"Step"},
+		},
+		"wrong remainder": {
+			proof:  present,
+			tamper: func(p *Proof) { p.HexRemainder = []byte{1, 2, 16} },
+			fields: []string{"HexRemainder"},
+		},
+		"wrong value": {
+			proof:  present,
+			tamper: func(p *Proof) { p.Value = []byte("foo") },
+			isErr:  true,
+		},
+		"value claimed absent": {
+			proof:  present,
+			tamper: func(p *Proof) { p.Value = nil },
+			isErr:  true,
+		},
+		"wrong raw": {
+			proof:  present,
+			tamper: func(p *Proof) { p.Steps[1].Raw = p.Steps[0].Raw },
+			isErr:  true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := *tc.proof
+			p.Steps = append([]Step{}, tc.proof.Steps...)
+			if tc.tamper != nil {
+				tc.tamper(&p)
+			}
+			mismatches, err := VerifyProofHints(&p, root)
+			if tc.isErr {
+				if err == nil {
+					t.Fatalf("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyProofHints: %+v", err)
+			}
+			if len(mismatches) != len(tc.fields) {
+				t.Fatalf("Got mismatches %v, expected %v", mismatches, tc.fields)
+			}
+			for i, m := range mismatches {
+				if m.Field != tc.fields[i] {
+					t.Fatalf("Got mismatch %s, expected %s", m, tc.fields[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package proof
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// History holds proofs for one key at a series of state roots
+type History struct {
+	Key   []byte
+	Roots []common.Hash
+	// Proofs has one entry per root. Where the key had no value, it is an
+	// absence proof with a nil Value.
+	Proofs []*Proof
+	// Nodes holds every node used by any of the proofs, once.
+	// The steps of all proofs share these encodings.
+	Nodes NodeMap
+}
+
+// ComputeHistory proves key at each of the given roots, reading nodes from db.
+// Nodes shared between roots (typically most of the trie between consecutive blocks)
+// are only fetched and decoded once.
+func ComputeHistory(db NodeReader, roots []common.Hash, key []byte) (*History, error) {
+	cache := stepCache{db: db, steps: map[common.Hash]Step{}}
+	proofs := make([]*Proof, len(roots))
+	for i, root := range roots {
+		proof, err := proveOrAbsence(cache.load, root, key)
+		if err != nil {
+			return nil, err
+		}
+		proofs[i] = proof
+	}
+
+	nodes := make(NodeMap, len(cache.steps))
+	for hash, step := range cache.steps {
+		nodes[hash] = step.Raw
+	}
+	return &History{Key: key, Roots: roots, Proofs: proofs, Nodes: nodes}, nil
+}
+
+// Value returns the proven value at the i-th root, nil if there was none
+func (h *History) Value(i int) []byte {
+	return h.Proofs[i].Value
+}
+
+// Changes returns the roots at which the value differs from the one at the previous root
+// (including the key appearing or disappearing). The first root is never included.
+func (h *History) Changes() []common.Hash {
+	var changed []common.Hash
+	for i := 1; i < len(h.Roots); i++ {
+		if !bytes.Equal(h.Value(i-1), h.Value(i)) || (h.Value(i-1) == nil) != (h.Value(i) == nil) {
+			changed = append(changed, h.Roots[i])
+		}
+	}
+	return changed
+}
+
+// stepCache remembers every node it loaded, so proofs against many roots
+// read and store shared nodes only once
+type stepCache struct {
+	db    NodeReader
+	steps map[common.Hash]Step
+}
+
+func (c *stepCache) load(hash hashNode) (Step, error) {
+	key := common.BytesToHash(hash)
+	if step, ok := c.steps[key]; ok {
+		return step, nil
+	}
+	step, err := readStep(c.db, hash)
+	if err != nil {
+		return Step{}, err
+	}
+	c.steps[key] = step
+	return step, nil
+}
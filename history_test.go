@@ -0,0 +1,102 @@
+package proof
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestComputeHistory(t *testing.T) {
+	diskdb := ethdb.NewMemDatabase()
+	triedb := trie.NewDatabase(diskdb)
+	tr, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		t.Fatalf("cannot create an empty trie: %s", err)
+	}
+	commit := func() common.Hash {
+		root, err := tr.Commit(nil)
+		if err != nil {
+			t.Fatalf("cannot commit: %s", err)
+		}
+		if err := triedb.Commit(root, false); err != nil {
+			t.Fatalf("cannot flush: %s", err)
+		}
+		return root
+	}
+
+	for i := 0; i < 500; i++ {
+		tr.Update(randBytes(32), randBytes(20))
+	}
+	key := randBytes(32)
+
+	// block 0: no value, block 1: created, block 2: unrelated change,
+	// block 3: updated, block 4: unrelated change
+	var roots []common.Hash
+	roots = append(roots, commit())
+	tr.Update(key, []byte("first"))
+	roots = append(roots, commit())
+	tr.Update(randBytes(32), randBytes(20))
+	roots = append(roots, commit())
+	tr.Update(key, []byte("second"))
+	roots = append(roots, commit())
+	tr.Update(randBytes(32), randBytes(20))
+	roots = append(roots, commit())
+
+	counter := &countingReader{db: diskdb}
+	history, err := ComputeHistory(counter, roots, key)
+	if err != nil {
+		t.Fatalf("ComputeHistory: %+v", err)
+	}
+
+	expected := [][]byte{nil, []byte("first"), []byte("first"), []byte("second"), []byte("second")}
+	steps := 0
+	for i, root := range roots {
+		if !bytes.Equal(expected[i], history.Value(i)) {
+			t.Fatalf("Value at %d is %q, expected %q", i, history.Value(i), expected[i])
+		}
+		// where the key has no value, the proof shows its absence
+		proof := history.Proofs[i]
+		if proof == nil {
+			t.Fatalf("Missing proof at %d", i)
+		}
+		steps += len(proof.Steps)
+		value, err := verifyProofOrAbsence(proof, root)
+		if err != nil {
+			t.Fatalf("Invalid proof at %d: %+v", i, err)
+		}
+		if !bytes.Equal(value, expected[i]) {
+			t.Fatalf("Proof at %d has value %q, expected %q", i, value, expected[i])
+		}
+	}
+
+	// the root differs each time, but most of the path is shared
+	if counter.reads >= steps {
+		t.Fatalf("Read %d nodes for %d steps, expected some reuse", counter.reads, steps)
+	}
+	if len(history.Nodes) != counter.reads {
+		t.Fatalf("Stored %d nodes, read %d", len(history.Nodes), counter.reads)
+	}
+
+	changes := history.Changes()
+	if len(changes) != 2 || changes[0] != roots[1] || changes[1] != roots[3] {
+		t.Fatalf("Unexpected changes %X", changes)
+	}
+}
+
+type countingReader struct {
+	db    NodeReader
+	reads int
+	// onRead, if set, is called on every read
+	onRead func()
+}
+
+func (c *countingReader) Get(key []byte) ([]byte, error) {
+	c.reads++
+	if c.onRead != nil {
+		c.onRead()
+	}
+	return c.db.Get(key)
+}
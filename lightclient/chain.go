@@ -0,0 +1,60 @@
+package lightclient
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	proof "github.com/confio/proofs-ethereum"
+)
+
+// VerifyHeaderChain decodes a contiguous run of RLP headers, ordered from newest to
+// oldest, and checks that the first one has hash trusted and every other one is the
+// parent of the one before it. The returned headers, in the same order, are then as
+// trusted as that hash, and their roots can be used to verify proofs of older blocks.
+//
+// Each hash is computed over the raw encoding given, so headers are never re-encoded,
+// and they are decoded with proof.DecodeHeader, which knows the fields of every fork.
+func VerifyHeaderChain(trusted common.Hash, headers [][]byte) ([]*proof.Header, error) {
+	if len(headers) == 0 {
+		return nil, fmt.Errorf("no headers")
+	}
+	chain := make([]*proof.Header, len(headers))
+	expected := trusted
+	for i, raw := range headers {
+		if hash := crypto.Keccak256Hash(raw); hash != expected {
+			return nil, fmt.Errorf("header %d has hash %X, expected %X", i, hash, expected)
+		}
+		header, err := proof.DecodeHeader(raw)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode header %d: %v", i, err)
+		}
+		if i > 0 && header.Number.Uint64()+1 != chain[i-1].Number.Uint64() {
+			return nil, fmt.Errorf("header %d has number %d, its child %d", i, header.Number, chain[i-1].Number)
+		}
+		chain[i] = header
+		expected = header.ParentHash
+	}
+	return chain, nil
+}
+
+// AddAncestors checks headers with VerifyHeaderChain, starting from the parent of the
+// trusted header with hash trusted, and stores them all as trusted headers
+func (k Keeper) AddAncestors(trusted common.Hash, headers [][]byte) error {
+	child, err := k.GetHeader(trusted)
+	if err != nil {
+		return err
+	}
+	chain, err := VerifyHeaderChain(child.ParentHash, headers)
+	if err != nil {
+		return err
+	}
+	if chain[0].Number.Uint64()+1 != child.Number.Uint64() {
+		return fmt.Errorf("parent has number %d, trusted header %d", chain[0].Number, child.Number)
+	}
+	for i, header := range chain {
+		k.setHeader(headers[i], header)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package lightclient
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+
+	proof "github.com/confio/proofs-ethereum"
+)
+
+func TestVerifyHeaderChain(t *testing.T) {
+	// block 3 has a receipt trie with one receipt to prove
+	receipts, err := trie.New(common.Hash{}, trie.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("trie.New: %+v", err)
+	}
+	key, _ := rlp.EncodeToBytes(uint(0))
+	receipts.Update(key, []byte("a receipt from block 3"))
+	receiptRoot := receipts.Hash()
+
+	headers := testChain(t, 10, func(h *proof.Header) {
+		if h.Number.Int64() == 3 {
+			h.ReceiptHash = receiptRoot
+		}
+	})
+	newest := headerHash(t, headers[len(headers)-1])
+	raw := encodeBackwards(t, headers)
+
+	chain, err := VerifyHeaderChain(newest, raw)
+	if err != nil {
+		t.Fatalf("VerifyHeaderChain: %+v", err)
+	}
+	if len(chain) != 10 || headerHash(t, chain[0]) != newest || chain[9].Number.Int64() != 0 {
+		t.Fatalf("Unexpected chain of %d headers", len(chain))
+	}
+	p, err := proof.ComputeProof(receipts, key)
+	if err != nil {
+		t.Fatalf("ComputeProof: %+v", err)
+	}
+	if err := proof.VerifyProof(p, chain[6].ReceiptHash); err != nil {
+		t.Fatalf("VerifyProof against block %d: %+v", chain[6].Number, err)
+	}
+
+	// a parent with a number that doesn't follow
+	skip := testChain(t, 3, func(h *proof.Header) {
+		if h.Number.Int64() == 0 {
+			h.Number = big.NewInt(5)
+		}
+	})
+
+	cases := map[string]struct {
+		trusted common.Hash
+		headers [][]byte
+	}{
+		"no headers":      {trusted: newest},
+		"wrong trusted":   {trusted: headerHash(t, headers[8]), headers: raw},
+		"missing header":  {trusted: newest, headers: append(append([][]byte{}, raw[:4]...), raw[5:]...)},
+		"wrong order":     {trusted: headerHash(t, headers[0]), headers: [][]byte{raw[9], raw[8]}},
+		"modified header": {trusted: newest, headers: append([][]byte{append(append([]byte{}, raw[0]...), 0)}, raw[1:]...)},
+		"invalid rlp":     {trusted: newest, headers: [][]byte{{0xc1}}},
+		"skipped number":  {trusted: headerHash(t, skip[2]), headers: encodeBackwards(t, skip)},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := VerifyHeaderChain(tc.trusted, tc.headers); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+	}
+}
+
+func TestAddAncestors(t *testing.T) {
+	headers := testChain(t, 5, nil)
+	k := NewKeeper(MemStore{})
+	if err := k.AddTrustedHeader(encodeHeader(t, headers[4])); err != nil {
+		t.Fatalf("AddTrustedHeader: %+v", err)
+	}
+	newest := headerHash(t, headers[4])
+	raw := encodeBackwards(t, headers[:4])
+
+	if err := k.AddAncestors(common.Hash{1}, raw); err == nil {
+		t.Fatalf("Expected error for an untrusted child")
+	}
+	if err := k.AddAncestors(newest, raw[1:]); err == nil {
+		t.Fatalf("Expected error for a chain not starting at the parent")
+	}
+	if _, err := k.GetHeaderByNumber(2); err == nil {
+		t.Fatalf("Failed calls added headers")
+	}
+
+	if err := k.AddAncestors(newest, raw); err != nil {
+		t.Fatalf("AddAncestors: %+v", err)
+	}
+	for _, header := range headers {
+		got, err := k.GetHeaderByNumber(header.Number.Uint64())
+		if err != nil {
+			t.Fatalf("GetHeaderByNumber: %+v", err)
+		}
+		if headerHash(t, got) != headerHash(t, header) {
+			t.Fatalf("Got header %X at height %d", headerHash(t, got), header.Number)
+		}
+	}
+}
+
+// testChain returns n linked headers from genesis, oldest first. modify may change
+// each header before its child links to it.
+func testChain(t *testing.T, n int, modify func(*proof.Header)) []*proof.Header {
+	t.Helper()
+	var headers []*proof.Header
+	parent := common.Hash{}
+	for i := 0; i < n; i++ {
+		h := testHeader(int64(i), common.BigToHash(big.NewInt(int64(i))), types.EmptyRootHash)
+		h.ParentHash = parent
+		if modify != nil {
+			modify(h)
+		}
+		headers = append(headers, h)
+		parent = headerHash(t, h)
+	}
+	return headers
+}
+
+// encodeBackwards encodes headers newest first, as VerifyHeaderChain takes them
+func encodeBackwards(t *testing.T, headers []*proof.Header) [][]byte {
+	t.Helper()
+	raw := make([][]byte, len(headers))
+	for i, h := range headers {
+		raw[len(headers)-1-i] = encodeHeader(t, h)
+	}
+	return raw
+}
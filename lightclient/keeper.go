@@ -0,0 +1,158 @@
+// Package lightclient keeps trusted Ethereum headers and verifies state,
+// storage and receipt proofs against them. It is written in the style of a
+// Cosmos SDK keeper, so chains consuming Ethereum data can embed it directly.
+package lightclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	proof "github.com/confio/proofs-ethereum"
+)
+
+var (
+	headerPrefix = []byte("header/") // header/<hash> -> rlp(header)
+	numberPrefix = []byte("number/") // number/<uint64 big endian> -> hash
+)
+
+// Keeper stores trusted headers and verifies proofs against them
+type Keeper struct {
+	store Store
+}
+
+// NewKeeper returns a Keeper on top of store
+func NewKeeper(store Store) Keeper {
+	return Keeper{store: store}
+}
+
+// AddTrustedHeader stores the RLP encoded header raw, making its roots available to
+// verify proofs. The header is stored as given, and its hash taken over these bytes,
+// so headers of any fork keep their block hash.
+// Deciding which headers to trust (relayer, governance, consensus proofs, ...)
+// is up to the caller.
+func (k Keeper) AddTrustedHeader(raw []byte) error {
+	header, err := proof.DecodeHeader(raw)
+	if err != nil {
+		return err
+	}
+	k.setHeader(raw, header)
+	return nil
+}
+
+// setHeader stores raw, the canonical encoding of header
+func (k Keeper) setHeader(raw []byte, header *proof.Header) {
+	hash := crypto.Keccak256Hash(raw)
+	k.store.Set(headerKey(hash), raw)
+	k.store.Set(numberKey(header.Number.Uint64()), hash[:])
+}
+
+// GetHeader returns the trusted header with the given hash
+func (k Keeper) GetHeader(hash common.Hash) (*proof.Header, error) {
+	bz := k.store.Get(headerKey(hash))
+	if bz == nil {
+		return nil, fmt.Errorf("no trusted header %X", hash)
+	}
+	header, err := proof.DecodeHeader(bz)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode header %X: %v", hash, err)
+	}
+	return header, nil
+}
+
+// GetHeaderByNumber returns the trusted header at the given height
+func (k Keeper) GetHeaderByNumber(number uint64) (*proof.Header, error) {
+	hash := k.store.Get(numberKey(number))
+	if hash == nil {
+		return nil, fmt.Errorf("no trusted header at height %d", number)
+	}
+	return k.GetHeader(common.BytesToHash(hash))
+}
+
+// VerifyAccount checks that accountProof holds the state of addr in the state
+// trie of the trusted header blockHash, and returns that state. An absence proof
+// shows the account doesn't exist, and returns an empty account.
+func (k Keeper) VerifyAccount(blockHash common.Hash, addr common.Address, accountProof *proof.Proof) (*proof.Account, error) {
+	header, err := k.GetHeader(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := proof.VerifyAccount(accountProof, header.Root, addr)
+	if err != nil {
+		return nil, err
+	}
+	return verified.Account, nil
+}
+
+// VerifyStorage checks accountProof as in VerifyAccount, then that storageProof holds
+// slot in the storage trie of that account, and returns the value of the slot.
+// An absence proof shows the slot holds zero.
+func (k Keeper) VerifyStorage(blockHash common.Hash, addr common.Address, slot common.Hash, accountProof, storageProof *proof.Proof) (common.Hash, error) {
+	account, err := k.VerifyAccount(blockHash, addr, accountProof)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	word, err := proof.VerifyStorage(storageProof, account.StorageRoot, proof.SlotAt(slot))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return word.Hash(), nil
+}
+
+// VerifyReceiptLog checks that receiptProof holds the receipt of transaction txIndex
+// in the receipt trie of the trusted header blockHash, and returns its logIndex-th log
+func (k Keeper) VerifyReceiptLog(blockHash common.Hash, txIndex, logIndex uint, receiptProof *proof.Proof) (*types.Log, error) {
+	header, err := k.GetHeader(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	// the receipt trie is keyed by the rlp of the transaction index
+	key, err := rlp.EncodeToBytes(txIndex)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(receiptProof.Key, key) {
+		return nil, fmt.Errorf("proof is not for transaction %d", txIndex)
+	}
+	if err := proof.VerifyProof(receiptProof, header.ReceiptHash); err != nil {
+		return nil, err
+	}
+
+	receipt, err := decodeReceipt(receiptProof.Value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode receipt %d: %v", txIndex, err)
+	}
+	if logIndex >= uint(len(receipt.Logs)) {
+		return nil, fmt.Errorf("receipt %d has %d logs, no log %d", txIndex, len(receipt.Logs), logIndex)
+	}
+	return receipt.Logs[logIndex], nil
+}
+
+// decodeReceipt parses the consensus encoding of a receipt. Typed receipts (EIP-2718)
+// are the type byte followed by the same fields as legacy ones.
+func decodeReceipt(value []byte) (*types.Receipt, error) {
+	if len(value) > 0 && value[0] <= 0x7f {
+		value = value[1:]
+	}
+	var receipt types.Receipt
+	if err := rlp.DecodeBytes(value, &receipt); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+func headerKey(hash common.Hash) []byte {
+	return append(append([]byte{}, headerPrefix...), hash[:]...)
+}
+
+func numberKey(number uint64) []byte {
+	key := make([]byte, len(numberPrefix)+8)
+	copy(key, numberPrefix)
+	binary.BigEndian.PutUint64(key[len(numberPrefix):], number)
+	return key
+}
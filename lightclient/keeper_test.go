@@ -0,0 +1,218 @@
+package lightclient
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+
+	proof "github.com/confio/proofs-ethereum"
+)
+
+func TestHeaders(t *testing.T) {
+	store := MemStore{}
+	k := NewKeeper(store)
+	raw := encodeHeader(t, testHeader(7, common.Hash{1}, common.Hash{2}))
+	if err := k.AddTrustedHeader(raw); err != nil {
+		t.Fatalf("AddTrustedHeader: %+v", err)
+	}
+	hash := crypto.Keccak256Hash(raw)
+	if !bytes.Equal(store.Get(headerKey(hash)), raw) {
+		t.Fatalf("Header not stored as given")
+	}
+
+	got, err := k.GetHeader(hash)
+	if err != nil {
+		t.Fatalf("GetHeader: %+v", err)
+	}
+	if headerHash(t, got) != hash {
+		t.Fatalf("Stored header changed")
+	}
+	got, err = k.GetHeaderByNumber(7)
+	if err != nil {
+		t.Fatalf("GetHeaderByNumber: %+v", err)
+	}
+	if headerHash(t, got) != hash {
+		t.Fatalf("Got header %X at height 7", headerHash(t, got))
+	}
+
+	if _, err := k.GetHeader(common.Hash{3}); err == nil {
+		t.Fatalf("Expected error for unknown header")
+	}
+	if _, err := k.GetHeaderByNumber(8); err == nil {
+		t.Fatalf("Expected error for unknown height")
+	}
+	if err := k.AddTrustedHeader(append(raw, 0)); err == nil {
+		t.Fatalf("Expected error for an invalid header")
+	}
+}
+
+func TestVerifyAccountAndStorage(t *testing.T) {
+	addr := common.HexToAddress("0xc0ffee")
+	other := common.HexToAddress("0xbeef")
+	slot := common.HexToHash("0x02")
+	word := common.HexToHash("0x1234")
+
+	diskdb := ethdb.NewMemDatabase()
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	if err != nil {
+		t.Fatalf("state.New: %+v", err)
+	}
+	statedb.SetNonce(addr, 3)
+	statedb.SetBalance(addr, big.NewInt(1000000))
+	statedb.SetCode(addr, []byte{0x60, 0x00})
+	statedb.SetState(addr, slot, word)
+	statedb.SetState(addr, common.HexToHash("0x03"), common.HexToHash("0x99"))
+	statedb.SetBalance(other, big.NewInt(5))
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("Commit: %+v", err)
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("Flush: %+v", err)
+	}
+
+	k := NewKeeper(MemStore{})
+	raw := encodeHeader(t, testHeader(1, root, types.EmptyRootHash))
+	if err := k.AddTrustedHeader(raw); err != nil {
+		t.Fatalf("AddTrustedHeader: %+v", err)
+	}
+	hash := crypto.Keccak256Hash(raw)
+
+	accountProof, err := proof.ComputeProofFromDB(diskdb, root, crypto.Keccak256(addr[:]))
+	if err != nil {
+		t.Fatalf("account proof: %+v", err)
+	}
+	account, err := k.VerifyAccount(hash, addr, accountProof)
+	if err != nil {
+		t.Fatalf("VerifyAccount: %+v", err)
+	}
+	if account.Nonce != 3 || account.Balance.Int64() != 1000000 {
+		t.Fatalf("Unexpected account %+v", account)
+	}
+	if account.CodeHash != crypto.Keccak256Hash([]byte{0x60, 0x00}) {
+		t.Fatalf("Unexpected code hash %X", account.CodeHash)
+	}
+
+	// proof for the wrong address, or against an untrusted block
+	if _, err := k.VerifyAccount(hash, other, accountProof); err == nil {
+		t.Fatalf("Expected error for proof of another address")
+	}
+	if _, err := k.VerifyAccount(common.Hash{1}, addr, accountProof); err == nil {
+		t.Fatalf("Expected error for untrusted block")
+	}
+
+	storageProof, err := proof.ComputeProofFromDB(diskdb, account.StorageRoot, crypto.Keccak256(slot[:]))
+	if err != nil {
+		t.Fatalf("storage proof: %+v", err)
+	}
+	value, err := k.VerifyStorage(hash, addr, slot, accountProof, storageProof)
+	if err != nil {
+		t.Fatalf("VerifyStorage: %+v", err)
+	}
+	if value != word {
+		t.Fatalf("Slot holds %X, expected %X", value, word)
+	}
+	if _, err := k.VerifyStorage(hash, addr, common.HexToHash("0x03"), accountProof, storageProof); err == nil {
+		t.Fatalf("Expected error for proof of another slot")
+	}
+}
+
+func TestVerifyReceiptLog(t *testing.T) {
+	tr := new(trie.Trie)
+	for i := uint(0); i < 3; i++ {
+		receipt := types.NewReceipt(nil, false, uint64(21000*(i+1)))
+		for j := 0; j < 2; j++ {
+			receipt.Logs = append(receipt.Logs, &types.Log{
+				Address: common.BigToAddress(big.NewInt(int64(i))),
+				Topics:  []common.Hash{common.BigToHash(big.NewInt(int64(j)))},
+				Data:    []byte{byte(i), byte(j)},
+			})
+		}
+		value, err := rlp.EncodeToBytes(receipt)
+		if err != nil {
+			t.Fatalf("Encoding receipt: %+v", err)
+		}
+		if i == 1 {
+			// EIP-1559 receipt
+			value = append([]byte{0x02}, value...)
+		}
+		key, _ := rlp.EncodeToBytes(i)
+		tr.Update(key, value)
+	}
+
+	k := NewKeeper(MemStore{})
+	raw := encodeHeader(t, testHeader(1, types.EmptyRootHash, tr.Hash()))
+	if err := k.AddTrustedHeader(raw); err != nil {
+		t.Fatalf("AddTrustedHeader: %+v", err)
+	}
+	hash := crypto.Keccak256Hash(raw)
+
+	for i := uint(0); i < 3; i++ {
+		key, _ := rlp.EncodeToBytes(i)
+		receiptProof, err := proof.ComputeProof(tr, key)
+		if err != nil {
+			t.Fatalf("ComputeProof: %+v", err)
+		}
+		log, err := k.VerifyReceiptLog(hash, i, 1, receiptProof)
+		if err != nil {
+			t.Fatalf("VerifyReceiptLog %d: %+v", i, err)
+		}
+		if !bytes.Equal(log.Data, []byte{byte(i), 1}) {
+			t.Fatalf("Unexpected log data %X", log.Data)
+		}
+		if _, err := k.VerifyReceiptLog(hash, i, 2, receiptProof); err == nil {
+			t.Fatalf("Expected error for missing log")
+		}
+		if _, err := k.VerifyReceiptLog(hash, i+1, 1, receiptProof); err == nil {
+			t.Fatalf("Expected error for proof of another transaction")
+		}
+	}
+}
+
+// testHeader returns a header with the fields of Cancun, which types.Header can't encode
+func testHeader(number int64, stateRoot, receiptRoot common.Hash) *proof.Header {
+	blobGas, excessBlobGas := uint64(131072), uint64(0)
+	withdrawalsRoot, beaconRoot := types.EmptyRootHash, common.Hash{0xbb}
+	return &proof.Header{
+		ParentHash:       common.Hash{0xaa},
+		UncleHash:        types.EmptyUncleHash,
+		Root:             stateRoot,
+		TxHash:           types.EmptyRootHash,
+		ReceiptHash:      receiptRoot,
+		Difficulty:       big.NewInt(0),
+		Number:           big.NewInt(number),
+		GasLimit:         30000000,
+		Time:             1710338135,
+		BaseFee:          big.NewInt(1000000000),
+		WithdrawalsRoot:  &withdrawalsRoot,
+		BlobGasUsed:      &blobGas,
+		ExcessBlobGas:    &excessBlobGas,
+		ParentBeaconRoot: &beaconRoot,
+	}
+}
+
+func encodeHeader(t *testing.T, header *proof.Header) []byte {
+	t.Helper()
+	raw, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatalf("EncodeToBytes: %+v", err)
+	}
+	return raw
+}
+
+func headerHash(t *testing.T, header *proof.Header) common.Hash {
+	t.Helper()
+	hash, err := header.Hash()
+	if err != nil {
+		t.Fatalf("Hash: %+v", err)
+	}
+	return hash
+}
@@ -0,0 +1,27 @@
+package lightclient
+
+// Store is the subset of a Cosmos SDK KVStore that the Keeper needs.
+// A prefix store from the host chain can be passed in directly.
+type Store interface {
+	Get(key []byte) []byte
+	Has(key []byte) bool
+	Set(key, value []byte)
+}
+
+// MemStore is an in-memory Store, for tests and tools
+type MemStore map[string][]byte
+
+var _ Store = MemStore(nil)
+
+func (m MemStore) Get(key []byte) []byte {
+	return m[string(key)]
+}
+
+func (m MemStore) Has(key []byte) bool {
+	_, ok := m[string(key)]
+	return ok
+}
+
+func (m MemStore) Set(key, value []byte) {
+	m[string(key)] = append([]byte{}, value...)
+}
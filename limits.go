@@ -0,0 +1,108 @@
+package proof
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Limits bounds the size of a single proof. Zero means no limit.
+type Limits struct {
+	// MaxDepth is the maximum number of steps in the proof
+	MaxDepth int
+	// MaxNodeBytes is the maximum RLP size of any one node on the path
+	MaxNodeBytes int
+	// MaxProofBytes is the maximum RLP size of all nodes on the path together
+	MaxProofBytes int
+}
+
+// LimitKind tells which of the Limits was exceeded
+type LimitKind int
+
+const (
+	LimitDepth LimitKind = iota
+	LimitNodeBytes
+	LimitProofBytes
+)
+
+func (k LimitKind) String() string {
+	switch k {
+	case LimitDepth:
+		return "depth"
+	case LimitNodeBytes:
+		return "node bytes"
+	case LimitProofBytes:
+		return "proof bytes"
+	default:
+		return fmt.Sprintf("LimitKind(%d)", int(k))
+	}
+}
+
+// LimitError is returned when a proof would exceed one of the Limits
+type LimitError struct {
+	Kind LimitKind
+	Max  int
+	// Got is the value that went over Max
+	Got int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("proof exceeds max %s: %d > %d", e.Kind, e.Got, e.Max)
+}
+
+// check returns a *LimitError if a proof of depth steps, whose latest node has nodeBytes
+// and whose nodes add up to proofBytes, goes over any limit
+func (l Limits) check(depth, nodeBytes, proofBytes int) error {
+	switch {
+	case l.MaxDepth > 0 && depth > l.MaxDepth:
+		return &LimitError{Kind: LimitDepth, Max: l.MaxDepth, Got: depth}
+	case l.MaxNodeBytes > 0 && nodeBytes > l.MaxNodeBytes:
+		return &LimitError{Kind: LimitNodeBytes, Max: l.MaxNodeBytes, Got: nodeBytes}
+	case l.MaxProofBytes > 0 && proofBytes > l.MaxProofBytes:
+		return &LimitError{Kind: LimitProofBytes, Max: l.MaxProofBytes, Got: proofBytes}
+	}
+	return nil
+}
+
+// ComputeProofFromDBContext is like ComputeProofFromDB, but gives up once ctx is done and
+// returns a *LimitError if the proof goes over any of limits. Both are checked before
+// every node is read from db, and the limits again once it is read, so a pathological
+// path only costs up to the node being read when they trip.
+//
+// A read already in progress is not interrupted, as NodeReader.Get takes no context,
+// so a backend that hangs still blocks this until the read returns.
+//
+// There is no such variant of ComputeProof: a trie.Trie reads its nodes internally,
+// out of reach of ctx and limits. Callers holding a trie pass the database it was
+// committed to here.
+func ComputeProofFromDBContext(ctx context.Context, db NodeReader, root common.Hash, key []byte, limits Limits) (*Proof, error) {
+	var depth, size int
+	load := func(hash hashNode) (Step, error) {
+		if err := ctx.Err(); err != nil {
+			return Step{}, err
+		}
+		if err := limits.check(depth+1, 0, size); err != nil {
+			return Step{}, err
+		}
+		step, err := readStep(db, hash)
+		if err != nil {
+			return Step{}, err
+		}
+		depth++
+		size += len(step.Raw)
+		if err := limits.check(depth, len(step.Raw), size); err != nil {
+			return Step{}, err
+		}
+		return step, nil
+	}
+
+	proof, _, err := proveFromDB(load, root, key)
+	if err != nil {
+		return nil, err
+	}
+	if proof == nil {
+		return nil, fmt.Errorf("No value found for key %X", key)
+	}
+	return proof, nil
+}
@@ -0,0 +1,121 @@
+package proof
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestComputeProofFromDBContext(t *testing.T) {
+	// same trie as "short node" in TestEthTrie, 5 steps to the query
+	items := []string{"aaaaaaa1", "aaaa2", "aaaaaaaaaaaaab", "C"}
+	query := []byte("aaaaaaaaaaaaab")
+
+	cases := map[string]struct {
+		limits Limits
+		kind   LimitKind
+		isErr  bool
+	}{
+		"no limits": {},
+		"loose limits": {
+			limits: Limits{MaxDepth: 5, MaxNodeBytes: 1000, MaxProofBytes: 5000},
+		},
+		"too deep": {
+			limits: Limits{MaxDepth: 4},
+			kind:   LimitDepth,
+			isErr:  true,
+		},
+		"node too big": {
+			limits: Limits{MaxNodeBytes: 10},
+			kind:   LimitNodeBytes,
+			isErr:  true,
+		},
+		"proof too big": {
+			limits: Limits{MaxProofBytes: 100},
+			kind:   LimitProofBytes,
+			isErr:  true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			db, root := diskStringTrie(t, items)
+			reads := &countingReader{db: db}
+			proof, err := ComputeProofFromDBContext(context.Background(), reads, root, query, tc.limits)
+			if tc.isErr {
+				lerr, ok := err.(*LimitError)
+				if !ok {
+					t.Fatalf("Expected *LimitError, got %T: %v", err, err)
+				}
+				if lerr.Kind != tc.kind {
+					t.Fatalf("Exceeded %s, expected %s", lerr.Kind, tc.kind)
+				}
+				// nothing is read past the node that went over
+				if tc.kind == LimitDepth && reads.reads != tc.limits.MaxDepth {
+					t.Fatalf("Read %d nodes with max depth %d", reads.reads, tc.limits.MaxDepth)
+				}
+				if tc.kind == LimitNodeBytes && reads.reads != 1 {
+					t.Fatalf("Read %d nodes after the root went over", reads.reads)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ComputeProofFromDBContext: %+v", err)
+			}
+			if err := VerifyProof(proof, root); err != nil {
+				t.Fatalf("Invalid proof %+v", err)
+			}
+		})
+	}
+}
+
+func TestComputeProofFromDBContextCancelled(t *testing.T) {
+	diskdb, tr, vals := diskTrie(t, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reads := &countingReader{db: diskdb}
+	_, err := ComputeProofFromDBContext(ctx, reads, tr.Hash(), vals[0].k, Limits{})
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if reads.reads != 0 {
+		t.Fatalf("Read %d nodes after cancellation", reads.reads)
+	}
+
+	// cancelled while the root is being read, no more nodes are read after it
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	reads = &countingReader{db: diskdb, onRead: cancel}
+	_, err = ComputeProofFromDBContext(ctx, reads, tr.Hash(), vals[0].k, Limits{})
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if reads.reads != 1 {
+		t.Fatalf("Read %d nodes, expected only the root", reads.reads)
+	}
+}
+
+// diskStringTrie is stringTrie committed to a database
+func diskStringTrie(t *testing.T, items []string) (ethdb.Database, common.Hash) {
+	diskdb := ethdb.NewMemDatabase()
+	triedb := trie.NewDatabase(diskdb)
+	tr, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		t.Fatalf("cannot create an empty trie: %s", err)
+	}
+	for _, s := range items {
+		tr.Update([]byte(s), []byte(s))
+	}
+	root, err := tr.Commit(nil)
+	if err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("cannot flush: %s", err)
+	}
+	return diskdb, root
+}
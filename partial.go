@@ -0,0 +1,207 @@
+package proof
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PartialTrie is the part of a trie revealed by a set of proofs against its root.
+// It grows as proofs are added, each checked against the root. Nodes are stored
+// decoded, by their hash, so only the ones reachable from the root count.
+type PartialTrie struct {
+	root  common.Hash
+	nodes map[common.Hash]Step
+}
+
+// NewPartialTrie returns a partial trie with the given root, knowing no nodes yet
+func NewPartialTrie(root common.Hash) *PartialTrie {
+	return &PartialTrie{root: root, nodes: make(map[common.Hash]Step)}
+}
+
+// Root is the root hash of the trie
+func (t *PartialTrie) Root() common.Hash {
+	return t.root
+}
+
+// AddProof checks proof against the root, from its key, value and raw nodes only,
+// and adds its nodes. It may be a proof of a value or of absence.
+func (t *PartialTrie) AddProof(proof *Proof) error {
+	w, err := walkPath(proof.Steps, t.root, proof.Key)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(w.value, proof.Value) {
+		return fmt.Errorf("proof value %X doesn't match %X stored in the trie", proof.Value, w.value)
+	}
+	for _, step := range w.steps {
+		step.Index = 0
+		t.nodes[common.BytesToHash(step.Hash)] = step
+	}
+	return nil
+}
+
+// KeyStatus tells what a PartialTrie knows about a key
+type KeyStatus int
+
+const (
+	// KeyUnknown keys are below a node no proof revealed
+	KeyUnknown KeyStatus = iota
+	// KeyAbsent keys are proven to have no value
+	KeyAbsent
+	// KeyPresent keys are proven to have a value
+	KeyPresent
+)
+
+func (s KeyStatus) String() string {
+	switch s {
+	case KeyUnknown:
+		return "unknown"
+	case KeyAbsent:
+		return "absent"
+	case KeyPresent:
+		return "present"
+	default:
+		return fmt.Sprintf("KeyStatus(%d)", int(s))
+	}
+}
+
+// errUnknownNode is returned by load for nodes no proof revealed
+var errUnknownNode = errors.New("unknown node")
+
+// Get looks key up in the known nodes. It returns the value and KeyPresent, nil and
+// KeyAbsent if the nodes show there is no value, or nil and KeyUnknown if the path
+// to key goes through a node no proof revealed.
+func (t *PartialTrie) Get(key []byte) ([]byte, KeyStatus) {
+	proof, _, err := proveFromDB(t.load, t.root, key)
+	switch {
+	case err != nil:
+		return nil, KeyUnknown
+	case proof == nil:
+		return nil, KeyAbsent
+	default:
+		return proof.Value, KeyPresent
+	}
+}
+
+func (t *PartialTrie) load(hash hashNode) (Step, error) {
+	step, ok := t.nodes[common.BytesToHash(hash)]
+	if !ok {
+		return Step{}, errUnknownNode
+	}
+	return step, nil
+}
+
+// KeyValue is a key and its value, fully known from the proofs
+type KeyValue struct {
+	Key   []byte
+	Value []byte
+}
+
+// Subtree is a node referenced by hash that no proof revealed. Prefix is the
+// path to it in hex nibbles, which may be an odd number of them.
+type Subtree struct {
+	Prefix []byte
+	Hash   common.Hash
+}
+
+// Iterate walks the known part of the trie in key order, calling onValue for every
+// key and value in it, and onUnknown for every subtree it can't see into.
+// Either may be nil.
+func (t *PartialTrie) Iterate(onValue func(KeyValue), onUnknown func(Subtree)) error {
+	if t.root == emptyRoot {
+		return nil
+	}
+	return t.iterate(nil, hashNode(t.root[:]), onValue, onUnknown)
+}
+
+func (t *PartialTrie) iterate(prefix []byte, n node, onValue func(KeyValue), onUnknown func(Subtree)) error {
+	switch n := n.(type) {
+	case nil:
+		return nil
+	case hashNode:
+		step, err := t.load(n)
+		if err != nil {
+			if onUnknown != nil {
+				onUnknown(Subtree{Prefix: append([]byte{}, prefix...), Hash: common.BytesToHash(n)})
+			}
+			return nil
+		}
+		return t.iterate(prefix, step.Step, onValue, onUnknown)
+	case *shortNode:
+		return t.iterate(concat(prefix, n.Key...), n.Val, onValue, onUnknown)
+	case *fullNode:
+		// the value slot holds the shortest key, so it comes first
+		if err := t.iterate(concat(prefix, 16), n.Children[16], onValue, onUnknown); err != nil {
+			return err
+		}
+		for i := 0; i < 16; i++ {
+			if err := t.iterate(concat(prefix, byte(i)), n.Children[i], onValue, onUnknown); err != nil {
+				return err
+			}
+		}
+		return nil
+	case valueNode:
+		if !hasTerm(prefix) || len(prefix)%2 != 1 {
+			return fmt.Errorf("value at invalid path %X", prefix)
+		}
+		if onValue != nil {
+			onValue(KeyValue{Key: hexToKeybytes(prefix), Value: append([]byte{}, n...)})
+		}
+		return nil
+	default:
+		return fmt.Errorf("Unknown type: %T", n)
+	}
+}
+
+// Values returns every key and value known, in key order
+func (t *PartialTrie) Values() ([]KeyValue, error) {
+	var values []KeyValue
+	err := t.Iterate(func(kv KeyValue) { values = append(values, kv) }, nil)
+	return values, err
+}
+
+// Unknown returns every subtree no proof revealed, in key order
+func (t *PartialTrie) Unknown() ([]Subtree, error) {
+	var unknown []Subtree
+	err := t.Iterate(nil, func(s Subtree) { unknown = append(unknown, s) })
+	return unknown, err
+}
+
+// Dump writes a line for every known key and value, and every unknown subtree, in key order
+func (t *PartialTrie) Dump(w io.Writer) error {
+	var werr error
+	write := func(format string, args ...interface{}) {
+		if werr == nil {
+			_, werr = fmt.Fprintf(w, format, args...)
+		}
+	}
+	write("root %X\n", t.root)
+	err := t.Iterate(
+		func(kv KeyValue) { write("value   %X = %X\n", kv.Key, kv.Value) },
+		func(s Subtree) { write("unknown %s -> %X\n", nibbles(s.Prefix), s.Hash) },
+	)
+	if err != nil {
+		return err
+	}
+	return werr
+}
+
+// nibbles prints a hex path one character per nibble
+func nibbles(hex []byte) string {
+	const digits = "0123456789abcdef"
+	s := make([]byte, len(hex))
+	for i, n := range hex {
+		s[i] = digits[n&0xf]
+	}
+	return string(s)
+}
+
+// concat returns prefix followed by more, without modifying prefix
+func concat(prefix []byte, more ...byte) []byte {
+	res := make([]byte, 0, len(prefix)+len(more))
+	return append(append(res, prefix...), more...)
+}
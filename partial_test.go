@@ -0,0 +1,203 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestPartialTrieIterate(t *testing.T) {
+	diskdb, tr, vals := diskTrie(t, 50)
+	root := tr.Hash()
+	sort.Slice(vals, func(i, j int) bool { return bytes.Compare(vals[i].k, vals[j].k) < 0 })
+
+	partial := NewPartialTrie(root)
+	if unknown, err := partial.Unknown(); err != nil || len(unknown) != 1 || unknown[0].Hash != root {
+		t.Fatalf("Expected only the root to be unknown, got %v (%v)", unknown, err)
+	}
+
+	// prove a few keys and an absence, values are too big to be embedded
+	proven := []kv{vals[3], vals[17], vals[40]}
+	for _, v := range proven {
+		proof, err := ComputeProofFromDB(diskdb, root, v.k)
+		if err != nil {
+			t.Fatalf("ComputeProofFromDB: %+v", err)
+		}
+		if err := partial.AddProof(proof); err != nil {
+			t.Fatalf("AddProof: %+v", err)
+		}
+	}
+	// an absence proof ending in a leaf would reveal another value, so end in a full node
+	var absent *Proof
+	for absent == nil {
+		p, err := ComputeAbsenceProofFromDB(diskdb, root, randBytes(32))
+		if err != nil {
+			t.Fatalf("ComputeAbsenceProofFromDB: %+v", err)
+		}
+		if _, ok := p.Steps[len(p.Steps)-1].Step.(*fullNode); ok {
+			absent = p
+		}
+	}
+	if err := partial.AddProof(absent); err != nil {
+		t.Fatalf("AddProof: %+v", err)
+	}
+
+	values, err := partial.Values()
+	if err != nil {
+		t.Fatalf("Values: %+v", err)
+	}
+	if len(values) != len(proven) {
+		t.Fatalf("Got %d values, expected %d", len(values), len(proven))
+	}
+	for i, v := range proven {
+		if !bytes.Equal(values[i].Key, v.k) || !bytes.Equal(values[i].Value, v.v) {
+			t.Fatalf("Got %X = %X, expected %X = %X", values[i].Key, values[i].Value, v.k, v.v)
+		}
+	}
+
+	// every other key is somewhere in the unknown subtrees
+	unknown, err := partial.Unknown()
+	if err != nil {
+		t.Fatalf("Unknown: %+v", err)
+	}
+	for _, v := range vals {
+		hexkey := keybytesToHex(v.k)
+		hidden := 0
+		for _, s := range unknown {
+			if bytes.HasPrefix(hexkey, s.Prefix) {
+				hidden++
+			}
+		}
+		isProven := false
+		for _, p := range proven {
+			isProven = isProven || bytes.Equal(p.k, v.k)
+		}
+		if isProven && hidden != 0 || !isProven && hidden != 1 {
+			t.Fatalf("Key %X is under %d unknown subtrees", v.k, hidden)
+		}
+	}
+
+	var dump strings.Builder
+	if err := partial.Dump(&dump); err != nil {
+		t.Fatalf("Dump: %+v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(dump.String()), "\n")
+	if len(lines) != 1+len(values)+len(unknown) {
+		t.Fatalf("Got %d lines in dump:\n%s", len(lines), dump.String())
+	}
+	if !strings.Contains(dump.String(), fmt.Sprintf("%X", proven[1].k)) {
+		t.Fatalf("Dump misses key %X:\n%s", proven[1].k, dump.String())
+	}
+
+	// proving everything leaves nothing unknown
+	for _, v := range vals {
+		proof, _ := ComputeProofFromDB(diskdb, root, v.k)
+		if err := partial.AddProof(proof); err != nil {
+			t.Fatalf("AddProof: %+v", err)
+		}
+	}
+	values, _ = partial.Values()
+	unknown, _ = partial.Unknown()
+	if len(values) != len(vals) || len(unknown) != 0 {
+		t.Fatalf("Got %d values and %d unknown subtrees", len(values), len(unknown))
+	}
+}
+
+func TestPartialTrieAddProof(t *testing.T) {
+	diskdb, tr, vals := diskTrie(t, 50)
+	root := tr.Hash()
+	proof, err := ComputeProofFromDB(diskdb, root, vals[0].k)
+	if err != nil {
+		t.Fatalf("ComputeProofFromDB: %+v", err)
+	}
+
+	if err := NewPartialTrie(common.Hash{1}).AddProof(proof); err == nil {
+		t.Fatalf("Expected error for a proof against another root")
+	}
+	wrong := *proof
+	wrong.Value = []byte("foo")
+	if err := NewPartialTrie(root).AddProof(&wrong); err == nil {
+		t.Fatalf("Expected error for a proof of another value")
+	}
+
+	// the empty trie is fully known from the start
+	empty := NewPartialTrie(emptyRoot)
+	if values, err := empty.Values(); err != nil || len(values) != 0 {
+		t.Fatalf("Got values %v (%v) in the empty trie", values, err)
+	}
+	if unknown, err := empty.Unknown(); err != nil || len(unknown) != 0 {
+		t.Fatalf("Got unknown %v (%v) in the empty trie", unknown, err)
+	}
+}
+
+func TestPartialTrieGet(t *testing.T) {
+	diskdb, tr, vals := diskTrie(t, 50)
+	root := tr.Hash()
+	partial := NewPartialTrie(root)
+	if _, status := partial.Get(vals[0].k); status != KeyUnknown {
+		t.Fatalf("Got %s before adding proofs", status)
+	}
+
+	proof, err := ComputeProofFromDB(diskdb, root, vals[5].k)
+	if err != nil {
+		t.Fatalf("ComputeProofFromDB: %+v", err)
+	}
+	if err := partial.AddProof(proof); err != nil {
+		t.Fatalf("AddProof: %+v", err)
+	}
+	missing := randBytes(32)
+	absent, err := ComputeAbsenceProofFromDB(diskdb, root, missing)
+	if err != nil {
+		t.Fatalf("ComputeAbsenceProofFromDB: %+v", err)
+	}
+	if err := partial.AddProof(absent); err != nil {
+		t.Fatalf("AddProof: %+v", err)
+	}
+
+	// find a key that is still behind an unrevealed node
+	var hidden kv
+	for _, v := range vals {
+		if _, status := partial.Get(v.k); status == KeyUnknown {
+			hidden = v
+			break
+		}
+	}
+	if hidden.k == nil {
+		t.Fatalf("Expected some key to be unknown")
+	}
+
+	cases := map[string]struct {
+		key    []byte
+		value  []byte
+		status KeyStatus
+	}{
+		"proven value":   {key: vals[5].k, value: vals[5].v, status: KeyPresent},
+		"proven absence": {key: missing, status: KeyAbsent},
+		"hidden key":     {key: hidden.k, status: KeyUnknown},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			value, status := partial.Get(tc.key)
+			if status != tc.status || !bytes.Equal(value, tc.value) {
+				t.Fatalf("Got %X (%s), expected %X (%s)", value, status, tc.value, tc.status)
+			}
+		})
+	}
+
+	// a new proof reveals the hidden key
+	proof, _ = ComputeProofFromDB(diskdb, root, hidden.k)
+	if err := partial.AddProof(proof); err != nil {
+		t.Fatalf("AddProof: %+v", err)
+	}
+	if value, status := partial.Get(hidden.k); status != KeyPresent || !bytes.Equal(value, hidden.v) {
+		t.Fatalf("Got %X (%s) after adding its proof", value, status)
+	}
+
+	if _, status := NewPartialTrie(emptyRoot).Get(vals[0].k); status != KeyAbsent {
+		t.Fatalf("Got %s in the empty trie", status)
+	}
+}
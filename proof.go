@@ -3,7 +3,6 @@ package proof
 import (
 	"bytes"
 	"fmt"
-	"log"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -32,6 +31,9 @@ type Step struct {
 	Index int
 	// Hash is set to the expected hash of this level
 	Hash []byte
+	// Raw is the original RLP encoding of Step, as stored in the database.
+	// Verification hashes these bytes rather than re-encoding Step.
+	Raw []byte
 }
 
 type Proof struct {
@@ -41,6 +43,9 @@ type Proof struct {
 	HexRemainder []byte
 }
 
+// RecoverKey rebuilds the key from the nibbles of every step. A value in the value
+// slot of a full node (Index 16) ends the key there, as it does for keys that are a
+// prefix of others. It panics if they don't make up a valid key, which VerifyProof checks first.
 func (p *Proof) RecoverKey() []byte {
 	var hexKey []byte
 	for _, step := range p.Steps {
@@ -58,19 +63,60 @@ func (p *Proof) RecoverKey() []byte {
 	return hexToKeybytes(hexKey)
 }
 
+// checkIndexes makes sure the steps and HexRemainder make up a valid hex key, so
+// RecoverKey won't panic: nibbles below 16, and the terminator only at the end
+func (p *Proof) checkIndexes() error {
+	var hexKey []byte
+	for i, step := range p.Steps {
+		switch t := step.Step.(type) {
+		case *shortNode:
+			hexKey = append(hexKey, t.Key...)
+		case *fullNode:
+			if step.Index < 0 || step.Index > 16 {
+				return fmt.Errorf("step %d has invalid index %d", i, step.Index)
+			}
+			hexKey = append(hexKey, byte(step.Index))
+		default:
+			return fmt.Errorf("step %d has unknown type: %T", i, step.Step)
+		}
+	}
+	hexKey = append(hexKey, p.HexRemainder...)
+
+	for i, nibble := range hexKey {
+		if nibble > 16 || (nibble == 16 && i != len(hexKey)-1) {
+			return fmt.Errorf("invalid nibble %d at %d in the key of the steps", nibble, i)
+		}
+	}
+	if hasTerm(hexKey) {
+		hexKey = hexKey[:len(hexKey)-1]
+	}
+	if len(hexKey)&1 != 0 {
+		return fmt.Errorf("steps have an odd number of key nibbles")
+	}
+	return nil
+}
+
 // ComputeProof returns the proof value for a key in given trie. Returned path
 // is the way from the value to the root of the tree.
 func ComputeProof(tr *trie.Trie, key []byte) (*Proof, error) {
-	record := ProofRecorder{}
-
 	value := tr.Get(key)
 	if value == nil {
 		return nil, fmt.Errorf("No value found for key %X", key)
 	}
+	return proveValue(tr, key, value)
+}
 
+// proveValue records the path to key, which is known to hold value.
+// It only reads from the trie, so it may run concurrently with other
+// reads (but not with Get, which may cache resolved nodes).
+func proveValue(tr *trie.Trie, key, value []byte) (*Proof, error) {
+	record := ProofRecorder{}
 	if err := tr.Prove(key, 0, &record); err != nil {
 		return nil, err
 	}
+	if err := record.Err(); err != nil {
+		return nil, err
+	}
 
 	proof, err := buildProof(key, value, record.Path())
 	if err != nil {
@@ -82,28 +128,33 @@ func ComputeProof(tr *trie.Trie, key []byte) (*Proof, error) {
 
 func VerifyProof(proof *Proof, rootHash common.Hash) error {
 	// let's make sure this is consistent with the claim - key and value should match
+	if err := proof.checkIndexes(); err != nil {
+		return err
+	}
 	recovered := proof.RecoverKey()
 	if !bytes.Equal(recovered, proof.Key) {
 		return fmt.Errorf("Proof.Key doesn't match key recovered from the steps")
 	}
 
-	// TODO: grab value and hexremainer from the last step
-
 	// first approach: let's go from top to bottom validating the hash matches expectations at each step
 	expected := rootHash[:]
+	var ref node
 	for i, step := range proof.Steps {
 		if !bytes.Equal(expected, step.Hash) {
 			return fmt.Errorf("step %d has different cached hash: %X\n  reference was %X", i, step.Hash, expected)
 		}
 
-		// calculate hash of this level, make sure it is expected
-		got := hashAnyNode(step.Step)
-		if !bytes.Equal(expected, got) {
-			return fmt.Errorf("step %d has different calculated hash: %X\n  it should be %X", i, got, expected)
+		// calculate hash of this level from the original encoding, make sure it is expected
+		decoded, err := checkStep(i, step, expected)
+		if err != nil {
+			return err
+		}
+		// and make sure the raw bytes really are the node we follow below
+		if !sameNode(decoded, step.Step) {
+			return fmt.Errorf("step %d raw encoding doesn't match the decoded node", i)
 		}
 
 		// find hash of next link and set expected
-		var ref node
 		switch t := step.Step.(type) {
 		case *fullNode:
 			ref = t.Children[step.Index]
@@ -113,18 +164,62 @@ func VerifyProof(proof *Proof, rootHash common.Hash) error {
 
 		if h, ok := ref.(hashNode); ok {
 			expected = h
-		} else {
-			// this should only be for the last step
-			expected = nil
+		} else if i < len(proof.Steps)-1 {
+			// an embedded node is part of this step's encoding, and can't hold a hash
+			// to any further step, so the path must end in it
+			return fmt.Errorf("step %d embeds the next node, but is followed by %d more steps", i, len(proof.Steps)-1-i)
 		}
 	}
+
+	// the last step holds the value, directly or in nodes embedded in it
+	value, err := embeddedValue(ref, proof.HexRemainder)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, proof.Value) {
+		return fmt.Errorf("proof value %X doesn't match %X stored in the trie", proof.Value, value)
+	}
 	return nil
 }
 
+// embeddedValue follows the rest of the key from the child of the last step,
+// through any number of embedded nodes, to the value. Those nodes were decoded
+// from the raw encoding of the step, so checking the key against each of them
+// checks the whole path. With a value in a full node's value slot (Index 16),
+// n is that value and nothing is left of the key.
+func embeddedValue(n node, hexkey []byte) ([]byte, error) {
+	for {
+		switch t := n.(type) {
+		case valueNode:
+			if len(hexkey) > 0 {
+				return nil, fmt.Errorf("key has %d nibbles left after the value", len(hexkey))
+			}
+			return t, nil
+		case *shortNode:
+			if len(hexkey) < len(t.Key) || !bytes.Equal(t.Key, hexkey[:len(t.Key)]) {
+				return nil, fmt.Errorf("embedded node key %X doesn't match key %X", t.Key, hexkey)
+			}
+			hexkey = hexkey[len(t.Key):]
+			n = t.Val
+		case *fullNode:
+			if len(hexkey) == 0 {
+				return nil, fmt.Errorf("key ends inside an embedded full node")
+			}
+			n = t.Children[hexkey[0]]
+			hexkey = hexkey[1:]
+		case hashNode:
+			return nil, fmt.Errorf("proof ends before reaching the value")
+		case nil:
+			return nil, fmt.Errorf("no value at the end of the proof")
+		default:
+			return nil, fmt.Errorf("Unknown type: %T", n)
+		}
+	}
+}
+
 // buildProof annotates the path of proofs, with the child we followed at each step
 func buildProof(key, value []byte, path []Step) (*Proof, error) {
 	hexkey := keybytesToHex(key)
-	log.Printf("hexkey: %X (%s)\n", hexkey, string(key))
 
 	for i, p := range path {
 		switch t := p.Step.(type) {
@@ -133,11 +228,13 @@ func buildProof(key, value []byte, path []Step) (*Proof, error) {
 			if len(hexkey) < len(t.Key) || !bytes.Equal(t.Key, hexkey[:len(t.Key)]) {
 				return nil, fmt.Errorf("Shortnode prefix %X doesn't match key %X", t.Key, hexkey)
 			}
-			fmt.Printf("short: %X\n", t.Key)
 			hexkey = hexkey[len(t.Key):]
 		case *fullNode:
-			fmt.Printf("next: %X\n", hexkey[0])
 			idx := int(hexkey[0])
+			// the value slot (16) is where keys that are a prefix of others end
+			if idx == 16 && i != len(path)-1 {
+				return nil, fmt.Errorf("key %X ends at step %d of %d", key, i, len(path))
+			}
 			hexkey = hexkey[1:]
 			path[i].Index = idx
 		default:
@@ -163,19 +260,35 @@ func buildProof(key, value []byte, path []Step) (*Proof, error) {
 // ProofRecorder is used to help us grab proofs
 type ProofRecorder struct {
 	path []Step
+	// err is the first error we hit, trie.Prove ignores the result of Put
+	err error
 }
 
 var _ ethdb.Putter = (*ProofRecorder)(nil)
 
 func (p *ProofRecorder) Put(hash, value []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.err = p.record(hash, value)
+	return p.err
+}
+
+func (p *ProofRecorder) record(hash, value []byte) error {
 	step, err := decodeNode(hash, value, 0)
 	if err != nil {
 		return err
 	}
-	p.path = append(p.path, Step{Step: step, Hash: hash})
+	raw := append([]byte{}, value...)
+	p.path = append(p.path, Step{Step: step, Hash: hash, Raw: raw})
 	return nil
 }
 
 func (p *ProofRecorder) Path() []Step {
 	return p.path
 }
+
+// Err returns the first error hit while recording, if any
+func (p *ProofRecorder) Err() error {
+	return p.err
+}
@@ -66,24 +66,8 @@ func TestEthTrie(t *testing.T) {
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-
-			db := ethdb.NewMemDatabase()
-			tr, err := trie.New(common.BytesToHash(nil), trie.NewDatabase(db))
-			if err != nil {
-				t.Fatalf("cannot create an empty trie: %s", err)
-			}
-
-			for _, s := range tc.items {
-				b := []byte(s)
-				tr.Update(b, b) // key == value
-			}
-
-			hash, err := tr.Commit(nil)
-			if err != nil {
-				t.Fatalf("cannot commit: %s", err)
-			} else {
-				t.Logf("commit hash of the trie: %X", hash)
-			}
+			tr, hash := stringTrie(t, tc.items)
+			t.Logf("commit hash of the trie: %X", hash)
 
 			proof, err := ComputeProof(tr, []byte(tc.query))
 			if tc.isError {
@@ -163,6 +147,26 @@ type kv struct {
 	v []byte
 }
 
+// stringTrie builds a committed trie where each item is stored with key == value
+func stringTrie(t *testing.T, items []string) (*trie.Trie, common.Hash) {
+	db := ethdb.NewMemDatabase()
+	tr, err := trie.New(common.BytesToHash(nil), trie.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("cannot create an empty trie: %s", err)
+	}
+
+	for _, s := range items {
+		b := []byte(s)
+		tr.Update(b, b) // key == value
+	}
+
+	hash, err := tr.Commit(nil)
+	if err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+	return tr, hash
+}
+
 func randomTrie(t *testing.T, n int) (*trie.Trie, []kv) {
 	db := ethdb.NewMemDatabase()
 	tr, err := trie.New(common.BytesToHash(nil), trie.NewDatabase(db))
@@ -199,3 +203,215 @@ func randBytes(n int) []byte {
 	rand.Read(r)
 	return r
 }
+
+func TestVerifyRawEncoding(t *testing.T) {
+	tr, keys := randomTrie(t, 500)
+	query := keys[len(keys)-3]
+	root := tr.Hash()
+
+	proof, err := ComputeProof(tr, query.k)
+	if err != nil {
+		t.Fatalf("ComputeProof: %+v", err)
+	}
+	if err := VerifyProof(proof, root); err != nil {
+		t.Fatalf("Invalid proof %+v", err)
+	}
+
+	// flip one byte of the raw encoding, hash no longer matches
+	last := len(proof.Steps) - 1
+	orig := proof.Steps[last].Raw
+	tampered := append([]byte{}, orig...)
+	tampered[len(tampered)-1] ^= 0x01
+	proof.Steps[last].Raw = tampered
+	if err := VerifyProof(proof, root); err == nil {
+		t.Fatalf("Expected error on tampered raw encoding")
+	}
+
+	// drop the raw encoding entirely
+	proof.Steps[last].Raw = nil
+	if err := VerifyProof(proof, root); err == nil {
+		t.Fatalf("Expected error on missing raw encoding")
+	}
+
+	proof.Steps[last].Raw = orig
+
+	// raw is fine, but the decoded root has a sibling we didn't commit to
+	top, ok := proof.Steps[0].Step.(*fullNode)
+	if !ok {
+		t.Fatalf("Expected fullNode at the root, got %T", proof.Steps[0].Step)
+	}
+	fake := top.copy()
+	fake.Children[(proof.Steps[0].Index+1)%16] = hashNode(bytes.Repeat([]byte{1}, 32))
+	proof.Steps[0].Step = fake
+	if err := VerifyProof(proof, root); err == nil {
+		t.Fatalf("Expected error on node not matching raw encoding")
+	}
+}
+
+func TestPrefixKeys(t *testing.T) {
+	// values are long enough for the nodes holding them to be hashed, not embedded
+	long := func(s string) string { return s + ": " + string(bytes.Repeat([]byte{'x'}, 40)) }
+	items := []string{"d", "do", "dog", "doge", "dogglesworth", "horse"}
+	tr, _ := stringTrie(t, nil)
+	for _, item := range items {
+		tr.Update([]byte(item), []byte(long(item)))
+	}
+	root := tr.Hash()
+
+	cases := map[string]struct {
+		key        string
+		branchSlot bool
+	}{
+		"shortest prefix":  {key: "d", branchSlot: true},
+		"middle prefix":    {key: "do", branchSlot: true},
+		"prefix of two":    {key: "dog", branchSlot: true},
+		"leaf under value": {key: "doge"},
+		"unrelated":        {key: "horse"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			proof, err := ComputeProof(tr, []byte(tc.key))
+			if err != nil {
+				t.Fatalf("ComputeProof: %+v", err)
+			}
+			last := proof.Steps[len(proof.Steps)-1]
+			if _, isFull := last.Step.(*fullNode); (isFull && last.Index == 16) != tc.branchSlot {
+				t.Fatalf("Got last step %T at index %d", last.Step, last.Index)
+			}
+			if !bytes.Equal(proof.RecoverKey(), []byte(tc.key)) {
+				t.Fatalf("Recovered key %q", proof.RecoverKey())
+			}
+			if err := VerifyProof(proof, root); err != nil {
+				t.Fatalf("Invalid proof %+v", err)
+			}
+
+			// the value must be the one in the slot
+			wrong := *proof
+			wrong.Value = []byte(long("cat"))
+			if err := VerifyProof(&wrong, root); err == nil {
+				t.Fatalf("Expected error for another value")
+			}
+		})
+	}
+
+	// "do" and "dog" share all steps but the last, so "do" can't claim the value of "dog"
+	do, _ := ComputeProof(tr, []byte("do"))
+	dog, _ := ComputeProof(tr, []byte("dog"))
+	dog.Key = []byte("do")
+	dog.Value = do.Value
+	if err := VerifyProof(dog, root); err == nil {
+		t.Fatalf("Expected error for the steps of another key")
+	}
+
+	// the value slot can only end the key
+	moved := *do
+	moved.Steps = append([]Step{}, do.Steps...)
+	for i := range moved.Steps {
+		if _, ok := moved.Steps[i].Step.(*fullNode); ok {
+			moved.Steps[i].Index = 16
+			break
+		}
+	}
+	if err := VerifyProof(&moved, root); err == nil {
+		t.Fatalf("Expected error for the value slot in the middle of the key")
+	}
+	bad := *do
+	bad.HexRemainder = []byte{16, 3}
+	if err := VerifyProof(&bad, root); err == nil {
+		t.Fatalf("Expected error for an invalid remainder")
+	}
+}
+
+func TestEmbeddedNodes(t *testing.T) {
+	cases := map[string]struct {
+		items    map[string]string
+		query    string
+		embedded int
+	}{
+		// root full node -> embedded leaf
+		"one level": {
+			items:    map[string]string{"\x10": "a", "\x20": "b"},
+			query:    "\x10",
+			embedded: 2,
+		},
+		// root full node -> embedded full node -> embedded leaf
+		"two levels": {
+			items:    map[string]string{"\x11": "a", "\x12": "b", "\x21": "c"},
+			query:    "\x12",
+			embedded: 4,
+		},
+		// root full node -> embedded extension -> embedded full node -> embedded leaf
+		"three levels": {
+			items:    map[string]string{"\x11\x11": "a", "\x11\x12": "b", "\x21": "c"},
+			query:    "\x11\x12",
+			embedded: 5,
+		},
+		// the value sits in the value slot of an embedded full node
+		"value slot": {
+			items:    map[string]string{"\x11": "a", "\x11\x12": "b", "\x21": "c"},
+			query:    "\x11",
+			embedded: 4,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tr, _ := stringTrie(t, nil)
+			for k, v := range tc.items {
+				tr.Update([]byte(k), []byte(v))
+			}
+			root := tr.Hash()
+			proof, err := ComputeProof(tr, []byte(tc.query))
+			if err != nil {
+				t.Fatalf("ComputeProof: %+v", err)
+			}
+			if len(proof.Steps) != 1 {
+				t.Fatalf("Expected the whole trie in the root, got %d steps", len(proof.Steps))
+			}
+			stats, err := AnalyzeProof(proof)
+			if err != nil {
+				t.Fatalf("AnalyzeProof: %+v", err)
+			}
+			if stats.EmbeddedChildren != tc.embedded {
+				t.Fatalf("Got %d embedded nodes, expected %d", stats.EmbeddedChildren, tc.embedded)
+			}
+			if err := VerifyProof(proof, root); err != nil {
+				t.Fatalf("Invalid proof %+v", err)
+			}
+
+			// everything below the root is checked, not just its hash
+			for other := range tc.items {
+				if other == tc.query {
+					continue
+				}
+				hexkey := keybytesToHex([]byte(other))
+				wrong := *proof
+				wrong.Steps = []Step{proof.Steps[0]}
+				wrong.Steps[0].Index = int(hexkey[0])
+				wrong.Key = []byte(other)
+				wrong.HexRemainder = hexkey[1:]
+				if err := VerifyProof(&wrong, root); err == nil {
+					t.Fatalf("Expected error for the value of %X claimed under %X", tc.items[tc.query], other)
+				}
+			}
+			wrong := *proof
+			wrong.Value = []byte("z")
+			if err := VerifyProof(&wrong, root); err == nil {
+				t.Fatalf("Expected error for another value")
+			}
+		})
+	}
+
+	// a step embedding the next node has to be the last one
+	tr, _ := stringTrie(t, nil)
+	tr.Update([]byte("\x10"), []byte("a"))
+	tr.Update([]byte("\x20"), []byte("b"))
+	proof, err := ComputeProof(tr, []byte("\x10"))
+	if err != nil {
+		t.Fatalf("ComputeProof: %+v", err)
+	}
+	proof.Steps = append(proof.Steps, proof.Steps[0])
+	if err := VerifyProof(proof, tr.Hash()); err == nil {
+		t.Fatalf("Expected error for steps after an embedded node")
+	}
+}
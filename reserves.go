@@ -0,0 +1,168 @@
+package proof
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ReservesProof proves the accounts of a list of addresses at one state root, eg. for
+// proof-of-reserves. The account proofs share most of their upper nodes, so rather than
+// a proof per address it holds every trie node they need once.
+type ReservesProof struct {
+	StateRoot common.Hash
+	Addresses []common.Address
+	// Nodes are the raw trie nodes of all the account proofs, each once, in the order
+	// the proofs first use them
+	Nodes [][]byte
+}
+
+// ComputeReservesProof proves the state of every address in addrs in the state trie with
+// the given root. Addresses may not repeat, as their balances would be counted twice.
+func ComputeReservesProof(db NodeReader, stateRoot common.Hash, addrs []common.Address) (*ReservesProof, error) {
+	if err := checkUnique(addrs); err != nil {
+		return nil, err
+	}
+	res := &ReservesProof{StateRoot: stateRoot, Addresses: addrs}
+	seen := make(map[common.Hash]bool)
+	for _, addr := range addrs {
+		account, err := ComputeAccountProof(db, stateRoot, addr)
+		if err != nil {
+			return nil, fmt.Errorf("account %X: %v", addr, err)
+		}
+		for _, step := range account.Proof.Steps {
+			hash := common.BytesToHash(makeHashNode(step.Raw))
+			if !seen[hash] {
+				seen[hash] = true
+				res.Nodes = append(res.Nodes, step.Raw)
+			}
+		}
+	}
+	return res, nil
+}
+
+// Reserves are the accounts a ReservesProof shows, in the order of its addresses
+type Reserves struct {
+	StateRoot common.Hash
+	Accounts  []*AccountProof
+	// Total is the sum of the balances of all accounts
+	Total *big.Int
+}
+
+// VerifyReservesProof checks that proof is for the trusted stateRoot, rebuilds the proof
+// of every account from the nodes, checks it with VerifyAccount, and sums the balances.
+// Addresses not in the state trie count as zero. Repeated addresses, repeated nodes and
+// nodes no account proof uses are rejected, so there is only one valid encoding of the
+// proof for a set of addresses.
+func VerifyReservesProof(proof *ReservesProof, stateRoot common.Hash) (*Reserves, error) {
+	if proof.StateRoot != stateRoot {
+		return nil, fmt.Errorf("proof is for state root %X, expected %X", proof.StateRoot, stateRoot)
+	}
+	if err := checkUnique(proof.Addresses); err != nil {
+		return nil, err
+	}
+	nodes := make(NodeMap, len(proof.Nodes))
+	for i, raw := range proof.Nodes {
+		hash := common.BytesToHash(makeHashNode(raw))
+		if _, ok := nodes[hash]; ok {
+			return nil, fmt.Errorf("node %d repeats node %X", i, hash)
+		}
+		nodes[hash] = raw
+	}
+
+	used := usedNodes{nodes: nodes, used: make(map[common.Hash]bool)}
+	res := &Reserves{StateRoot: stateRoot, Total: new(big.Int)}
+	for _, addr := range proof.Addresses {
+		computed, err := ComputeAccountProof(used, stateRoot, addr)
+		if err != nil {
+			return nil, fmt.Errorf("account %X: %v", addr, err)
+		}
+		account, err := VerifyAccount(computed.Proof, stateRoot, addr)
+		if err != nil {
+			return nil, fmt.Errorf("account %X: %v", addr, err)
+		}
+		res.Accounts = append(res.Accounts, account)
+		res.Total.Add(res.Total, account.Account.Balance)
+	}
+	if len(used.used) != len(nodes) {
+		return nil, fmt.Errorf("%d nodes are not used by any account", len(nodes)-len(used.used))
+	}
+	return res, nil
+}
+
+// usedNodes records which nodes were read
+type usedNodes struct {
+	nodes NodeMap
+	used  map[common.Hash]bool
+}
+
+func (u usedNodes) Get(key []byte) ([]byte, error) {
+	raw, err := u.nodes.Get(key)
+	if err == nil {
+		u.used[common.BytesToHash(key)] = true
+	}
+	return raw, err
+}
+
+func checkUnique(addrs []common.Address) error {
+	seen := make(map[common.Address]bool, len(addrs))
+	for _, addr := range addrs {
+		if seen[addr] {
+			return fmt.Errorf("address %X is listed twice", addr)
+		}
+		seen[addr] = true
+	}
+	return nil
+}
+
+// reservesJSON is the export format of a ReservesProof
+type reservesJSON struct {
+	StateRoot common.Hash      `json:"stateRoot"`
+	Addresses []common.Address `json:"addresses"`
+	Nodes     []hexutil.Bytes  `json:"nodes"`
+}
+
+// MarshalJSON exports the proof for auditors, with the nodes as hex strings
+func (p *ReservesProof) MarshalJSON() ([]byte, error) {
+	enc := reservesJSON{StateRoot: p.StateRoot, Addresses: p.Addresses}
+	for _, raw := range p.Nodes {
+		enc.Nodes = append(enc.Nodes, raw)
+	}
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON reads the format of MarshalJSON
+func (p *ReservesProof) UnmarshalJSON(data []byte) error {
+	var dec reservesJSON
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return fmt.Errorf("invalid reserves proof: %v", err)
+	}
+	*p = ReservesProof{StateRoot: dec.StateRoot, Addresses: dec.Addresses}
+	for _, raw := range dec.Nodes {
+		p.Nodes = append(p.Nodes, raw)
+	}
+	return nil
+}
+
+// WriteCSV writes a line with the state of every account, then one with the total,
+// for auditors to check against their own records
+func (r *Reserves) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"address", "exists", "nonce", "balance"})
+	for _, account := range r.Accounts {
+		cw.Write([]string{
+			account.Address.Hex(),
+			fmt.Sprint(account.Exists),
+			fmt.Sprint(account.Account.Nonce),
+			account.Account.Balance.String(),
+		})
+	}
+	cw.Write([]string{"total", "", "", r.Total.String()})
+	cw.Flush()
+	return cw.Error()
+}
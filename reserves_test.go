@@ -0,0 +1,94 @@
+package proof
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+)
+
+func TestReservesProof(t *testing.T) {
+	diskdb, _, root := testState(t, func(*state.StateDB) {})
+
+	// testState gives address 1000+i a balance of i, for i below 200
+	var addrs []common.Address
+	for i := int64(0); i < 50; i++ {
+		addrs = append(addrs, common.BigToAddress(big.NewInt(1000+3*i)))
+	}
+	missing := common.HexToAddress("0xdeadbeef")
+	addrs = append(addrs, missing)
+
+	proof, err := ComputeReservesProof(diskdb, root, addrs)
+	if err != nil {
+		t.Fatalf("ComputeReservesProof: %+v", err)
+	}
+	steps := 0
+	for _, addr := range addrs {
+		account, _ := ComputeAccountProof(diskdb, root, addr)
+		steps += len(account.Proof.Steps)
+	}
+	if len(proof.Nodes) >= steps {
+		t.Fatalf("Expected shared nodes to be stored once, got %d nodes for %d steps", len(proof.Nodes), steps)
+	}
+
+	// the export format round trips
+	exported, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatalf("Marshal: %+v", err)
+	}
+	var imported ReservesProof
+	if err := json.Unmarshal(exported, &imported); err != nil {
+		t.Fatalf("Unmarshal: %+v", err)
+	}
+
+	reserves, err := VerifyReservesProof(&imported, root)
+	if err != nil {
+		t.Fatalf("VerifyReservesProof: %+v", err)
+	}
+	// 3*0 + 3*1 + ... + 3*49
+	if reserves.Total.Int64() != 3*49*50/2 {
+		t.Fatalf("Got total %s", reserves.Total)
+	}
+	if len(reserves.Accounts) != len(addrs) || reserves.Accounts[len(addrs)-1].Exists {
+		t.Fatalf("Expected %d accounts, the last one missing", len(addrs))
+	}
+	var report strings.Builder
+	if err := reserves.WriteCSV(&report); err != nil {
+		t.Fatalf("WriteCSV: %+v", err)
+	}
+	if !strings.Contains(report.String(), "\ntotal,,,3675\n") || !strings.Contains(report.String(), missing.Hex()+",false,0,0\n") {
+		t.Fatalf("Unexpected report:\n%s", report.String())
+	}
+
+	if _, err := ComputeReservesProof(diskdb, root, []common.Address{addrs[0], addrs[1], addrs[0]}); err == nil {
+		t.Fatalf("Expected error for a repeated address")
+	}
+
+	cases := map[string]func(p *ReservesProof){
+		"wrong root":       func(p *ReservesProof) { p.StateRoot = common.Hash{1} },
+		"missing node":     func(p *ReservesProof) { p.Nodes = p.Nodes[:len(p.Nodes)-1] },
+		"repeated node":    func(p *ReservesProof) { p.Nodes = append(p.Nodes, p.Nodes[0]) },
+		"unused node":      func(p *ReservesProof) { p.Nodes = append(p.Nodes, []byte{0xc2, 0x80, 0x80}) },
+		"repeated address": func(p *ReservesProof) { p.Addresses = append(p.Addresses, p.Addresses[3]) },
+		"corrupt node":     func(p *ReservesProof) { p.Nodes[1] = append([]byte{}, p.Nodes[1][:len(p.Nodes[1])-1]...) },
+	}
+	for name, tamper := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := *proof
+			p.Addresses = append([]common.Address{}, proof.Addresses...)
+			p.Nodes = append([][]byte{}, proof.Nodes...)
+			tamper(&p)
+			if _, err := VerifyReservesProof(&p, root); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+	}
+
+	// a valid proof, but not for the root we trust
+	if _, err := VerifyReservesProof(proof, common.Hash{1}); err == nil {
+		t.Fatalf("Expected error for another state root")
+	}
+}
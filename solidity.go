@@ -0,0 +1,307 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// VerifierABI is the ABI of the contract produced by SolidityVerifier
+const VerifierABI = `[{"constant":true,"inputs":[{"name":"root","type":"bytes32"},{"name":"key","type":"bytes"},{"name":"value","type":"bytes"},{"name":"proof","type":"bytes"}],"name":"verify","outputs":[{"name":"","type":"bool"}],"payable":false,"stateMutability":"pure","type":"function"}]`
+
+// SolidityVerifier returns the source of a Solidity contract with the given name,
+// exposing verify(root, key, value, proof) which checks the same proofs as VerifyProof.
+// The proof argument is the RLP list of raw nodes, as returned by Proof.NodeList.
+func SolidityVerifier(contractName string) (string, error) {
+	var buf bytes.Buffer
+	err := verifierTemplate.Execute(&buf, struct{ Name string }{contractName})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// NodeList returns the RLP list of the raw encoding of every step, root first.
+// This is the proof argument for the Solidity verifier.
+func (p *Proof) NodeList() ([]byte, error) {
+	nodes := make([]rlp.RawValue, len(p.Steps))
+	for i, step := range p.Steps {
+		if len(step.Raw) == 0 {
+			return nil, fmt.Errorf("step %d is missing the raw node encoding", i)
+		}
+		nodes[i] = step.Raw
+	}
+	return rlp.EncodeToBytes(nodes)
+}
+
+// PackVerifyCall returns the calldata to check proof against rootHash
+// with the Solidity verifier
+func PackVerifyCall(proof *Proof, rootHash common.Hash) ([]byte, error) {
+	nodes, err := proof.NodeList()
+	if err != nil {
+		return nil, err
+	}
+	return verifierABI.Pack("verify", [32]byte(rootHash), proof.Key, proof.Value, nodes)
+}
+
+// UnpackVerifyResult parses the return data of a verify call
+func UnpackVerifyResult(output []byte) (bool, error) {
+	var valid bool
+	err := verifierABI.Unpack(&valid, "verify", output)
+	return valid, err
+}
+
+var verifierABI = mustParseABI(VerifierABI)
+
+func mustParseABI(def string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(def))
+	if err != nil {
+		panic(fmt.Sprintf("invalid abi: %v", err))
+	}
+	return parsed
+}
+
+var verifierTemplate = template.Must(template.New("verifier").Parse(verifierSource))
+
+const verifierSource = `// SPDX-License-Identifier: Apache-2.0
+// Generated by github.com/confio/proofs-ethereum, do not edit.
+pragma solidity >=0.5.0 <0.9.0;
+
+contract {{.Name}} {
+    // Item points to one RLP item (prefix included) in memory
+    struct Item {
+        uint256 len;
+        uint256 ptr;
+    }
+
+    uint256 private constant FAIL = 0;
+    uint256 private constant NEXT = 1;
+    uint256 private constant FOUND = 2;
+
+    // verify returns whether proof shows that key holds value in the trie with the given root.
+    // proof is the RLP list of all hashed nodes on the path, starting with the root.
+    function verify(bytes32 root, bytes memory key, bytes memory value, bytes memory proof)
+        public pure returns (bool)
+    {
+        // the trie holds no empty values, an empty value slot means the key is absent
+        if (value.length == 0) {
+            return false;
+        }
+        Item[] memory nodes = toList(toItem(proof));
+        bytes memory path = toNibbles(key);
+        bytes32 expected = root;
+        uint256 pos = 0;
+        for (uint256 i = 0; i < nodes.length; i++) {
+            if (keccakOf(nodes[i]) != expected) {
+                return false;
+            }
+            uint256 status;
+            (status, expected, pos) = walk(nodes[i], path, pos, value);
+            if (status == FAIL) {
+                return false;
+            }
+            if (status == FOUND) {
+                return i == nodes.length - 1;
+            }
+        }
+        return false;
+    }
+
+    // walk follows path from nibble pos through node and any nodes embedded in it.
+    // It returns NEXT with the hash of the next node, or FOUND if value is at the end of path.
+    function walk(Item memory node, bytes memory path, uint256 pos, bytes memory value)
+        private pure returns (uint256, bytes32, uint256)
+    {
+        Item[] memory fields = toList(node);
+        Item memory child;
+        if (fields.length == 17) {
+            if (pos == path.length) {
+                return (equal(toBytes(fields[16]), value) ? FOUND : FAIL, bytes32(0), pos);
+            }
+            child = fields[uint8(path[pos])];
+            pos++;
+        } else if (fields.length == 2) {
+            (bool leaf, bytes memory nibbles) = compactNibbles(toBytes(fields[0]));
+            if (path.length - pos < nibbles.length) {
+                return (FAIL, bytes32(0), pos);
+            }
+            for (uint256 i = 0; i < nibbles.length; i++) {
+                if (path[pos + i] != nibbles[i]) {
+                    return (FAIL, bytes32(0), pos);
+                }
+            }
+            pos += nibbles.length;
+            if (leaf) {
+                bool found = pos == path.length && equal(toBytes(fields[1]), value);
+                return (found ? FOUND : FAIL, bytes32(0), pos);
+            }
+            child = fields[1];
+        } else {
+            return (FAIL, bytes32(0), pos);
+        }
+
+        if (isList(child)) {
+            return walk(child, path, pos, value);
+        }
+        bytes memory ref = toBytes(child);
+        if (ref.length != 32) {
+            return (FAIL, bytes32(0), pos);
+        }
+        bytes32 next;
+        assembly {
+            next := mload(add(ref, 0x20))
+        }
+        return (NEXT, next, pos);
+    }
+
+    function toNibbles(bytes memory key) private pure returns (bytes memory nibbles) {
+        nibbles = new bytes(key.length * 2);
+        for (uint256 i = 0; i < key.length; i++) {
+            nibbles[2 * i] = bytes1(uint8(key[i]) >> 4);
+            nibbles[2 * i + 1] = bytes1(uint8(key[i]) & 0x0f);
+        }
+    }
+
+    // compactNibbles decodes a hex-prefix encoded key
+    function compactNibbles(bytes memory enc) private pure returns (bool leaf, bytes memory nibbles) {
+        require(enc.length > 0, "empty key");
+        uint8 flag = uint8(enc[0]) >> 4;
+        require(flag < 4, "invalid key flag");
+        leaf = flag >= 2;
+        bool odd = (flag & 1) == 1;
+        nibbles = new bytes((enc.length - 1) * 2 + (odd ? 1 : 0));
+        uint256 j = 0;
+        if (odd) {
+            nibbles[j++] = bytes1(uint8(enc[0]) & 0x0f);
+        }
+        for (uint256 i = 1; i < enc.length; i++) {
+            nibbles[j++] = bytes1(uint8(enc[i]) >> 4);
+            nibbles[j++] = bytes1(uint8(enc[i]) & 0x0f);
+        }
+    }
+
+    function toItem(bytes memory data) private pure returns (Item memory) {
+        uint256 ptr;
+        assembly {
+            ptr := add(data, 0x20)
+        }
+        return Item(data.length, ptr);
+    }
+
+    function isList(Item memory item) private pure returns (bool) {
+        if (item.len == 0) {
+            return false;
+        }
+        uint256 ptr = item.ptr;
+        uint256 b0;
+        assembly {
+            b0 := byte(0, mload(ptr))
+        }
+        return b0 >= 0xc0;
+    }
+
+    function toList(Item memory item) private pure returns (Item[] memory list) {
+        require(isList(item), "not a list");
+        (uint256 ptr, uint256 len) = payload(item);
+        uint256 end = ptr + len;
+        uint256 count = 0;
+        for (uint256 p = ptr; p < end; p += itemLength(p)) {
+            count++;
+        }
+        list = new Item[](count);
+        for (uint256 i = 0; i < count; i++) {
+            uint256 l = itemLength(ptr);
+            list[i] = Item(l, ptr);
+            ptr += l;
+        }
+        require(ptr == end, "invalid list length");
+    }
+
+    function toBytes(Item memory item) private pure returns (bytes memory out) {
+        require(!isList(item), "not a string");
+        (uint256 src, uint256 len) = payload(item);
+        out = new bytes(len);
+        uint256 dst;
+        assembly {
+            dst := add(out, 0x20)
+        }
+        for (; len >= 32; len -= 32) {
+            assembly {
+                mstore(dst, mload(src))
+            }
+            src += 32;
+            dst += 32;
+        }
+        if (len > 0) {
+            uint256 mask = 256 ** (32 - len) - 1;
+            assembly {
+                mstore(dst, or(and(mload(src), not(mask)), and(mload(dst), mask)))
+            }
+        }
+    }
+
+    // itemLength returns the size of the item starting at ptr, prefix included
+    function itemLength(uint256 ptr) private pure returns (uint256) {
+        uint256 b0;
+        assembly {
+            b0 := byte(0, mload(ptr))
+        }
+        if (b0 < 0x80) {
+            return 1;
+        } else if (b0 < 0xb8) {
+            return 1 + b0 - 0x80;
+        } else if (b0 < 0xc0) {
+            return 1 + (b0 - 0xb7) + longLength(ptr, b0 - 0xb7);
+        } else if (b0 < 0xf8) {
+            return 1 + b0 - 0xc0;
+        }
+        return 1 + (b0 - 0xf7) + longLength(ptr, b0 - 0xf7);
+    }
+
+    // payload returns where the content of item starts and how long it is
+    function payload(Item memory item) private pure returns (uint256, uint256) {
+        uint256 ptr = item.ptr;
+        uint256 b0;
+        assembly {
+            b0 := byte(0, mload(ptr))
+        }
+        uint256 offset;
+        if (b0 < 0x80) {
+            offset = 0;
+        } else if (b0 < 0xb8) {
+            offset = 1;
+        } else if (b0 < 0xc0) {
+            offset = 1 + b0 - 0xb7;
+        } else if (b0 < 0xf8) {
+            offset = 1;
+        } else {
+            offset = 1 + b0 - 0xf7;
+        }
+        return (ptr + offset, item.len - offset);
+    }
+
+    // longLength reads the big endian length of size bytes following the prefix at ptr
+    function longLength(uint256 ptr, uint256 size) private pure returns (uint256 len) {
+        assembly {
+            len := div(mload(add(ptr, 1)), exp(256, sub(32, size)))
+        }
+    }
+
+    function keccakOf(Item memory item) private pure returns (bytes32 hash) {
+        uint256 ptr = item.ptr;
+        uint256 len = item.len;
+        assembly {
+            hash := keccak256(ptr, len)
+        }
+    }
+
+    function equal(bytes memory a, bytes memory b) private pure returns (bool) {
+        return a.length == b.length && keccak256(a) == keccak256(b);
+    }
+}
+`
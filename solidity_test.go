@@ -0,0 +1,314 @@
+package proof
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestPackVerifyCall(t *testing.T) {
+	tr, keys := randomTrie(t, 500)
+	query := keys[len(keys)-3]
+	root := tr.Hash()
+
+	proof, err := ComputeProof(tr, query.k)
+	if err != nil {
+		t.Fatalf("ComputeProof: %+v", err)
+	}
+
+	// node list holds exactly the raw steps
+	list, err := proof.NodeList()
+	if err != nil {
+		t.Fatalf("NodeList: %+v", err)
+	}
+	var raws []rlp.RawValue
+	if err := rlp.DecodeBytes(list, &raws); err != nil {
+		t.Fatalf("Decoding node list: %+v", err)
+	}
+	if len(raws) != len(proof.Steps) {
+		t.Fatalf("Got %d nodes, expected %d", len(raws), len(proof.Steps))
+	}
+	for i, raw := range raws {
+		if !bytes.Equal(proof.Steps[i].Raw, raw) {
+			t.Fatalf("Node %d doesn't match step", i)
+		}
+	}
+
+	call, err := PackVerifyCall(proof, root)
+	if err != nil {
+		t.Fatalf("PackVerifyCall: %+v", err)
+	}
+	method := verifierABI.Methods["verify"]
+	if !bytes.Equal(method.Id(), call[:4]) {
+		t.Fatalf("Wrong selector %X", call[:4])
+	}
+	args, err := method.Inputs.UnpackValues(call[4:])
+	if err != nil {
+		t.Fatalf("Unpacking call: %+v", err)
+	}
+	if args[0].([32]byte) != root || !bytes.Equal(args[1].([]byte), query.k) ||
+		!bytes.Equal(args[2].([]byte), query.v) || !bytes.Equal(args[3].([]byte), list) {
+		t.Fatalf("Call arguments don't round trip")
+	}
+}
+
+func TestSolidityVerifierSource(t *testing.T) {
+	src, err := SolidityVerifier("EthProofVerifier")
+	if err != nil {
+		t.Fatalf("SolidityVerifier: %+v", err)
+	}
+	if !strings.Contains(src, "contract EthProofVerifier {") {
+		t.Fatalf("Contract name not in source")
+	}
+	if !strings.Contains(src, "function verify(bytes32 root, bytes memory key, bytes memory value, bytes memory proof)") {
+		t.Fatalf("Source doesn't match VerifierABI")
+	}
+}
+
+// TestSolidityVerifierBytecode makes sure testdata/verifier.bin-runtime, which
+// TestSolidityVerifierEVM runs, is still what solc makes of SolidityVerifier.
+// It needs solc in the PATH.
+func TestSolidityVerifierBytecode(t *testing.T) {
+	code := compileVerifier(t)
+	expected, err := readVerifier()
+	if err != nil {
+		t.Fatalf("Cannot read the compiled verifier: %v\n%s", err, regenerateVerifier)
+	}
+	if !bytes.Equal(code, expected) {
+		t.Fatalf("Generated contract changed: %s", regenerateVerifier)
+	}
+}
+
+// TestSolidityVerifierEVM runs the compiled contract from testdata in go-ethereum's EVM
+// and makes sure it agrees with VerifyProof.
+//
+// It calls the contract on a bare vm.EVM rather than the simulated backend, which
+// needs packages of go-ethereum (through p2p and mclock) whose dependencies are not
+// part of this module.
+func TestSolidityVerifierEVM(t *testing.T) {
+	evm := newVerifierEVM(t)
+
+	for i := 0; i < 5; i++ {
+		tr, keys := randomTrie(t, 500)
+		root := tr.Hash()
+		for _, query := range []kv{keys[0], keys[150], keys[len(keys)-3]} {
+			proof, err := ComputeProof(tr, query.k)
+			if err != nil {
+				t.Fatalf("ComputeProof: %+v", err)
+			}
+			if err := VerifyProof(proof, root); err != nil {
+				t.Fatalf("Invalid proof %+v", err)
+			}
+			if !evm.verify(t, proof, root) {
+				t.Fatalf("Contract rejects valid proof for %X", query.k)
+			}
+
+			// contract must reject what VerifyProof rejects
+			wrongValue := *proof
+			wrongValue.Value = append([]byte{}, proof.Value...)
+			wrongValue.Value[0] ^= 1
+			if evm.verify(t, &wrongValue, root) {
+				t.Fatalf("Contract accepts wrong value for %X", query.k)
+			}
+			wrongRoot := root
+			wrongRoot[0] ^= 1
+			if VerifyProof(proof, wrongRoot) == nil || evm.verify(t, proof, wrongRoot) {
+				t.Fatalf("Wrong root accepted for %X", query.k)
+			}
+		}
+	}
+
+	for name, tc := range emptySlotProofs(t) {
+		if VerifyProof(tc.proof, tc.root) == nil || evm.verify(t, tc.proof, tc.root) {
+			t.Fatalf("Empty value accepted for %s", name)
+		}
+	}
+}
+
+func TestVerifyEmptySlot(t *testing.T) {
+	for name, tc := range emptySlotProofs(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := VerifyProof(tc.proof, tc.root); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+	}
+}
+
+type emptySlotProof struct {
+	proof *Proof
+	root  common.Hash
+}
+
+// emptySlotProofs claims an empty value for keys whose path ends at a branch with an
+// empty value slot, which holds no value, not an empty one
+func emptySlotProofs(t *testing.T) map[string]emptySlotProof {
+	value := bytes.Repeat([]byte{0xab}, 40)
+	cases := map[string]struct {
+		keys  []string
+		query string
+	}{
+		// an extension over "d", then a branch
+		"branch below the root": {keys: []string{"d\x10", "d\x20"}, query: "d"},
+		"branch at the root":    {keys: []string{"\x10", "\x20"}, query: ""},
+	}
+
+	proofs := make(map[string]emptySlotProof, len(cases))
+	for name, tc := range cases {
+		diskdb := ethdb.NewMemDatabase()
+		triedb := trie.NewDatabase(diskdb)
+		tr, err := trie.New(common.Hash{}, triedb)
+		if err != nil {
+			t.Fatalf("trie.New: %+v", err)
+		}
+		for _, k := range tc.keys {
+			tr.Update([]byte(k), value)
+		}
+		root, err := tr.Commit(nil)
+		if err != nil {
+			t.Fatalf("Commit: %+v", err)
+		}
+		if err := triedb.Commit(root, false); err != nil {
+			t.Fatalf("Flush: %+v", err)
+		}
+
+		proof, err := computeProofOrAbsence(diskdb, root, []byte(tc.query))
+		if err != nil {
+			t.Fatalf("computeProofOrAbsence: %+v", err)
+		}
+		last := proof.Steps[len(proof.Steps)-1]
+		if _, ok := last.Step.(*fullNode); !ok || last.Index != 16 {
+			t.Fatalf("%s: path doesn't end in a branch value slot", name)
+		}
+		proof.Value = []byte{}
+		proofs[name] = emptySlotProof{proof: proof, root: root}
+	}
+	return proofs
+}
+
+type verifierEVM struct {
+	evm  *vm.EVM
+	addr common.Address
+}
+
+// solcFlags compile the verifier for the pinned EVM, which predates shanghai, so
+// there is no PUSH0. Without the metadata hash, the code only depends on the source
+// and the compiler.
+var solcFlags = []string{"--evm-version", "petersburg", "--optimize", "--metadata-hash", "none"}
+
+var regenerateVerifier = fmt.Sprintf("regenerate testdata/verifier.bin-runtime with\n"+
+	"solc %s --bin-runtime Verifier.sol\nwhere Verifier.sol is SolidityVerifier(\"Verifier\")",
+	strings.Join(solcFlags, " "))
+
+// readVerifier reads the committed runtime code of SolidityVerifier("Verifier")
+func readVerifier() ([]byte, error) {
+	raw, err := ioutil.ReadFile(filepath.Join("testdata", "verifier.bin-runtime"))
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimSpace(string(raw)))
+}
+
+// loadVerifier is readVerifier for the tests running the contract. They are
+// skipped while the compiled contract isn't committed, which needs solc.
+func loadVerifier(t *testing.T) []byte {
+	code, err := readVerifier()
+	if os.IsNotExist(err) {
+		t.Skipf("No compiled verifier, %s", regenerateVerifier)
+	}
+	if err != nil {
+		t.Fatalf("Invalid compiled verifier: %v", err)
+	}
+	return code
+}
+
+// compileVerifier compiles SolidityVerifier("Verifier") to runtime code with solc,
+// skipping the test if solc isn't installed
+func compileVerifier(t *testing.T) []byte {
+	solc, err := exec.LookPath("solc")
+	if err != nil {
+		t.Skip("solc not found in PATH")
+	}
+
+	dir, err := ioutil.TempDir("", "verifier")
+	if err != nil {
+		t.Fatalf("TempDir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	src, err := SolidityVerifier("Verifier")
+	if err != nil {
+		t.Fatalf("SolidityVerifier: %+v", err)
+	}
+	file := filepath.Join(dir, "Verifier.sol")
+	if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatalf("WriteFile: %+v", err)
+	}
+	args := append(append([]string{}, solcFlags...), "--bin-runtime", file)
+	out, err := exec.Command(solc, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("solc: %v\n%s", err, out)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	code, err := hex.DecodeString(strings.TrimSpace(lines[len(lines)-1]))
+	if err != nil {
+		t.Fatalf("Cannot parse solc output: %v\n%s", err, out)
+	}
+	return code
+}
+
+func newVerifierEVM(t *testing.T) *verifierEVM {
+	code := loadVerifier(t)
+
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("state.New: %+v", err)
+	}
+	addr := common.HexToAddress("0x1000")
+	statedb.SetCode(addr, code)
+
+	ctx := vm.Context{
+		CanTransfer: func(vm.StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(vm.StateDB, common.Address, common.Address, *big.Int) {},
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		GasPrice:    big.NewInt(0),
+		GasLimit:    100000000,
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(0),
+		Difficulty:  big.NewInt(0),
+	}
+	evm := vm.NewEVM(ctx, statedb, params.AllEthashProtocolChanges, vm.Config{})
+	return &verifierEVM{evm: evm, addr: addr}
+}
+
+func (v *verifierEVM) verify(t *testing.T, proof *Proof, root common.Hash) bool {
+	call, err := PackVerifyCall(proof, root)
+	if err != nil {
+		t.Fatalf("PackVerifyCall: %+v", err)
+	}
+	out, _, err := v.evm.StaticCall(vm.AccountRef(common.Address{}), v.addr, call, 10000000)
+	if err != nil {
+		// reverts count as rejection
+		return false
+	}
+	valid, err := UnpackVerifyResult(out)
+	if err != nil {
+		t.Fatalf("UnpackVerifyResult: %+v", err)
+	}
+	return valid
+}
@@ -0,0 +1,129 @@
+package proof
+
+import "fmt"
+
+// Calldata gas per byte since Istanbul (EIP-2028)
+const (
+	CalldataZeroByteGas    = 4
+	CalldataNonZeroByteGas = 16
+)
+
+// NodeKind is the type of a trie node on the proof path
+type NodeKind int
+
+const (
+	BranchNode NodeKind = iota
+	ExtensionNode
+	LeafNode
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case BranchNode:
+		return "branch"
+	case ExtensionNode:
+		return "extension"
+	case LeafNode:
+		return "leaf"
+	default:
+		return fmt.Sprintf("NodeKind(%d)", int(k))
+	}
+}
+
+// MarshalText makes NodeKind keys readable in JSON
+func (k NodeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// ProofStats describes the size of a proof, and what it would cost to relay on-chain
+type ProofStats struct {
+	// Depth is the number of steps
+	Depth int
+	// StepBytes is the raw size of each step, root first
+	StepBytes []int
+	// TotalBytes is the raw size of all steps together
+	TotalBytes int
+	// Kinds counts the steps of each kind. Nodes embedded in a step are not counted.
+	Kinds map[NodeKind]int
+	// HashedChildren counts references to other nodes by hash, EmbeddedChildren
+	// nodes inlined in their parent (under 32 bytes), in all steps
+	HashedChildren   int
+	EmbeddedChildren int
+	// CalldataBytes is the size of the node list passed to the on-chain verifier,
+	// and CalldataGas what those bytes cost in a transaction
+	CalldataBytes int
+	CalldataGas   uint64
+}
+
+// AnalyzeProof measures proof from the raw encoding of its steps
+func AnalyzeProof(proof *Proof) (*ProofStats, error) {
+	stats := ProofStats{
+		Depth:     len(proof.Steps),
+		StepBytes: make([]int, len(proof.Steps)),
+		Kinds:     make(map[NodeKind]int),
+	}
+	for i, step := range proof.Steps {
+		if len(step.Raw) == 0 {
+			return nil, fmt.Errorf("step %d is missing the raw node encoding", i)
+		}
+		decoded, err := decodeNode(nil, step.Raw, 0)
+		if err != nil {
+			return nil, fmt.Errorf("step %d cannot decode raw node: %v", i, err)
+		}
+		stats.StepBytes[i] = len(step.Raw)
+		stats.TotalBytes += len(step.Raw)
+		stats.Kinds[kindOf(decoded)]++
+		stats.countChildren(decoded)
+	}
+
+	nodes, err := proof.NodeList()
+	if err != nil {
+		return nil, err
+	}
+	stats.CalldataBytes = len(nodes)
+	stats.CalldataGas = CalldataGas(nodes)
+	return &stats, nil
+}
+
+// countChildren adds up the hashed and embedded children of n, and of the nodes embedded in it
+func (s *ProofStats) countChildren(n node) {
+	var children []node
+	switch t := n.(type) {
+	case *fullNode:
+		children = t.Children[:]
+	case *shortNode:
+		children = []node{t.Val}
+	}
+	for _, child := range children {
+		switch child.(type) {
+		case hashNode:
+			s.HashedChildren++
+		case *fullNode, *shortNode:
+			s.EmbeddedChildren++
+			s.countChildren(child)
+		}
+	}
+}
+
+// CalldataGas returns the gas charged for data sent as transaction calldata
+func CalldataGas(data []byte) uint64 {
+	var gas uint64
+	for _, b := range data {
+		if b == 0 {
+			gas += CalldataZeroByteGas
+		} else {
+			gas += CalldataNonZeroByteGas
+		}
+	}
+	return gas
+}
+
+func kindOf(n PathStep) NodeKind {
+	if short, ok := n.(*shortNode); ok {
+		if hasTerm(short.Key) {
+			return LeafNode
+		}
+		return ExtensionNode
+	}
+	return BranchNode
+}
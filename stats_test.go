@@ -0,0 +1,104 @@
+package proof
+
+import (
+	"testing"
+)
+
+func TestAnalyzeProof(t *testing.T) {
+	cases := map[string]struct {
+		items    []string
+		query    string
+		kinds    map[NodeKind]int
+		hashed   int
+		embedded int
+	}{
+		"single leaf": {
+			items: []string{"more than 16 bytes here..."},
+			query: "more than 16 bytes here...",
+			kinds: map[NodeKind]int{LeafNode: 1},
+		},
+		"embedded full node": {
+			// the root is an extension for the shared nibble 6, embedding a branch of small leaves
+			items:    []string{"a", "b", "c"},
+			query:    "a",
+			kinds:    map[NodeKind]int{ExtensionNode: 1},
+			embedded: 4,
+		},
+		"two branches": {
+			// small leaves are embedded in the branches, only the one we follow is hashed
+			items:    []string{"a", "B", "7", "ASDF", "    000    ", "fooBAR"},
+			query:    "fooBAR",
+			kinds:    map[NodeKind]int{BranchNode: 2},
+			hashed:   1,
+			embedded: 7,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tr, _ := stringTrie(t, tc.items)
+			proof, err := ComputeProof(tr, []byte(tc.query))
+			if err != nil {
+				t.Fatalf("ComputeProof: %+v", err)
+			}
+			stats, err := AnalyzeProof(proof)
+			if err != nil {
+				t.Fatalf("AnalyzeProof: %+v", err)
+			}
+
+			if stats.Depth != len(proof.Steps) || len(stats.StepBytes) != len(proof.Steps) {
+				t.Fatalf("Got depth %d with %d step sizes, expected %d", stats.Depth, len(stats.StepBytes), len(proof.Steps))
+			}
+			total := 0
+			for i, step := range proof.Steps {
+				if stats.StepBytes[i] != len(step.Raw) {
+					t.Fatalf("Step %d: got %d bytes, expected %d", i, stats.StepBytes[i], len(step.Raw))
+				}
+				total += len(step.Raw)
+			}
+			if stats.TotalBytes != total {
+				t.Fatalf("Got %d total bytes, expected %d", stats.TotalBytes, total)
+			}
+			for kind, count := range tc.kinds {
+				if stats.Kinds[kind] != count {
+					t.Fatalf("Got %d %s nodes, expected %d (%v)", stats.Kinds[kind], kind, count, stats.Kinds)
+				}
+			}
+			if stats.HashedChildren != tc.hashed || stats.EmbeddedChildren != tc.embedded {
+				t.Fatalf("Got %d hashed and %d embedded children, expected %d and %d",
+					stats.HashedChildren, stats.EmbeddedChildren, tc.hashed, tc.embedded)
+			}
+
+			nodes, err := proof.NodeList()
+			if err != nil {
+				t.Fatalf("NodeList: %+v", err)
+			}
+			if stats.CalldataBytes != len(nodes) || stats.CalldataGas != CalldataGas(nodes) {
+				t.Fatalf("Got %d calldata bytes costing %d gas", stats.CalldataBytes, stats.CalldataGas)
+			}
+		})
+	}
+
+	if _, err := AnalyzeProof(&Proof{Steps: []Step{{}}}); err == nil {
+		t.Fatalf("Expected error for a step without raw encoding")
+	}
+}
+
+func TestCalldataGas(t *testing.T) {
+	cases := map[string]struct {
+		data   []byte
+		expect uint64
+	}{
+		"empty":    {data: nil, expect: 0},
+		"zeros":    {data: []byte{0, 0, 0}, expect: 12},
+		"non-zero": {data: []byte{1, 0xff}, expect: 32},
+		"mixed":    {data: []byte{0xf8, 0, 0x51, 0}, expect: 40},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := CalldataGas(tc.data); got != tc.expect {
+				t.Fatalf("Got %d gas, expected %d", got, tc.expect)
+			}
+		})
+	}
+}
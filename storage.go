@@ -0,0 +1,165 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// Slot locates a variable in contract storage, following the Solidity storage layout.
+// It is a storage slot, and for variables packed with others, the bytes they take in it.
+//
+// Start from the slot of a state variable, then derive the slot of mapping values,
+// array elements and struct members from it, eg. balances[addr] where balances is
+// the 4th state variable is StateVar(3).MapAddress(addr).
+type Slot struct {
+	slot common.Hash
+	// offset is counted in bytes from the low-order (right) end of the word, like Solidity does
+	offset uint
+	size   uint
+}
+
+// StateVar returns the slot of the state variable declared at position n of the layout
+func StateVar(n uint64) Slot {
+	return SlotAt(common.BigToHash(new(big.Int).SetUint64(n)))
+}
+
+// SlotAt returns the whole word at the given storage slot
+func SlotAt(slot common.Hash) Slot {
+	return Slot{slot: slot, size: common.HashLength}
+}
+
+// Hash returns the storage slot
+func (s Slot) Hash() common.Hash {
+	return s.slot
+}
+
+// TrieKey returns the key of the slot in the storage trie, which is a secure trie
+func (s Slot) TrieKey() []byte {
+	return makeHashNode(s.slot[:])
+}
+
+// Offset returns where the variable starts in the slot, in bytes from the low-order end
+func (s Slot) Offset() uint {
+	return s.offset
+}
+
+// Size returns how many bytes of the slot the variable takes
+func (s Slot) Size() uint {
+	return s.size
+}
+
+// Packed returns the variable of size bytes at offset (from the low-order end) in this slot,
+// as Solidity packs variables smaller than a word
+func (s Slot) Packed(offset, size uint) (Slot, error) {
+	if size == 0 || size > common.HashLength || offset > common.HashLength-size {
+		return Slot{}, fmt.Errorf("cannot pack %d bytes at offset %d in a slot", size, offset)
+	}
+	return Slot{slot: s.slot, offset: offset, size: size}, nil
+}
+
+// Field returns the struct member n slots after the start of the struct stored at s
+func (s Slot) Field(n uint64) Slot {
+	return SlotAt(addSlot(s.slot, new(big.Int).SetUint64(n)))
+}
+
+// MapKey returns the value stored under key in the mapping at s, key being the
+// 32 byte word of a value type key
+func (s Slot) MapKey(key common.Hash) Slot {
+	return SlotAt(keccakHash(key[:], s.slot[:]))
+}
+
+// MapAddress returns the value stored under addr in the mapping at s
+func (s Slot) MapAddress(addr common.Address) Slot {
+	return s.MapKey(common.BytesToHash(addr[:]))
+}
+
+// MapInt returns the value stored under n in the mapping at s. This works
+// for any uintN or intN key, negative numbers being taken in two's complement.
+func (s Slot) MapInt(n *big.Int) Slot {
+	return s.MapKey(common.BigToHash(math.U256(new(big.Int).Set(n))))
+}
+
+// MapFixedBytes returns the value stored under a bytesN key in the mapping at s.
+// Unlike numbers, those are padded on the right.
+func (s Slot) MapFixedBytes(key []byte) (Slot, error) {
+	if len(key) == 0 || len(key) > common.HashLength {
+		return Slot{}, fmt.Errorf("invalid bytesN key length %d", len(key))
+	}
+	return s.MapKey(common.BytesToHash(common.RightPadBytes(key, common.HashLength))), nil
+}
+
+// MapBytes returns the value stored under a string or bytes key in the mapping at s.
+// Those keys are hashed as they are, without padding.
+func (s Slot) MapBytes(key []byte) Slot {
+	return SlotAt(keccakHash(key, s.slot[:]))
+}
+
+// Element returns element i of the dynamic array at s, for elements of size bytes
+// (use 32 times the number of slots for structs)
+func (s Slot) Element(i uint64, size uint) (Slot, error) {
+	return elementAt(keccakHash(s.slot[:]), i, size)
+}
+
+// FixedElement returns element i of the fixed size array at s, for elements of size bytes
+// (use 32 times the number of slots for structs)
+func (s Slot) FixedElement(i uint64, size uint) (Slot, error) {
+	return elementAt(s.slot, i, size)
+}
+
+// elementAt finds element i of an array starting at base. Elements smaller than a word
+// are packed as many as fit in each slot, others start on a new slot.
+func elementAt(base common.Hash, i uint64, size uint) (Slot, error) {
+	if size == 0 {
+		return Slot{}, fmt.Errorf("array elements cannot have size 0")
+	}
+	if size >= common.HashLength {
+		slots := (uint64(size) + common.HashLength - 1) / common.HashLength
+		n := new(big.Int).Mul(new(big.Int).SetUint64(i), new(big.Int).SetUint64(slots))
+		return SlotAt(addSlot(base, n)), nil
+	}
+	perSlot := uint64(common.HashLength / size)
+	start := SlotAt(addSlot(base, new(big.Int).SetUint64(i/perSlot)))
+	return start.Packed(uint(i%perSlot)*size, size)
+}
+
+// ComputeStorageProof proves the value of slot in the storage trie with the given root.
+// Slots that were never written hold zero, and get an absence proof.
+func ComputeStorageProof(db NodeReader, storageRoot common.Hash, slot Slot) (*Proof, error) {
+	return computeProofOrAbsence(db, storageRoot, slot.TrieKey())
+}
+
+// VerifyStorage checks that proof shows the value of slot in the storage trie with the given root,
+// and returns the variable slot points to. An absence proof shows the slot holds zero.
+func VerifyStorage(proof *Proof, storageRoot common.Hash, slot Slot) (StorageWord, error) {
+	if !bytes.Equal(proof.Key, slot.TrieKey()) {
+		return StorageWord{}, fmt.Errorf("proof is not for slot %X", slot.slot)
+	}
+	value, err := verifyProofOrAbsence(proof, storageRoot)
+	if err != nil {
+		return StorageWord{}, err
+	}
+	word, err := DecodeStorageValue(value)
+	if err != nil {
+		return StorageWord{}, err
+	}
+	return word.At(slot)
+}
+
+// addSlot returns base + n, wrapping around like the EVM does
+func addSlot(base common.Hash, n *big.Int) common.Hash {
+	sum := new(big.Int).Add(base.Big(), n)
+	return common.BigToHash(math.U256(sum))
+}
+
+// keccakHash hashes the concatenation of data
+func keccakHash(data ...[]byte) common.Hash {
+	var buf []byte
+	for _, d := range data {
+		buf = append(buf, d...)
+	}
+	return common.BytesToHash(makeHashNode(buf))
+}
@@ -0,0 +1,175 @@
+package proof
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSlotDerivation(t *testing.T) {
+	holder := common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+	spender := common.HexToAddress("0x0000000000000000000000000000000000c0ffee")
+	pad := func(b []byte) []byte { return common.LeftPadBytes(b, 32) }
+	// keccak256(uint256(0)) and keccak256(uint256(1)), where the data of arrays at slot 0 and 1 start
+	array0 := common.HexToHash("290decd9548b62a8d60345a988386fc84ba6bc95484008f6362f93160ef3e563")
+	array1 := common.HexToHash("b10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6")
+
+	mustSlot := func(s Slot, err error) Slot {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Deriving slot: %+v", err)
+		}
+		return s
+	}
+
+	cases := map[string]struct {
+		slot   Slot
+		expect common.Hash
+		offset uint
+		size   uint
+	}{
+		"state variable": {
+			slot:   StateVar(5),
+			expect: common.BigToHash(big.NewInt(5)),
+			size:   32,
+		},
+		"mapping(address => uint) at 3": {
+			slot:   StateVar(3).MapAddress(holder),
+			expect: crypto.Keccak256Hash(pad(holder[:]), pad([]byte{3})),
+			size:   32,
+		},
+		"mapping(address => mapping(address => uint)) at 4": {
+			slot:   StateVar(4).MapAddress(holder).MapAddress(spender),
+			expect: crypto.Keccak256Hash(pad(spender[:]), crypto.Keccak256(pad(holder[:]), pad([]byte{4}))),
+			size:   32,
+		},
+		"mapping(int => uint) negative key": {
+			slot:   StateVar(1).MapInt(big.NewInt(-1)),
+			expect: crypto.Keccak256Hash(bytes.Repeat([]byte{0xff}, 32), pad([]byte{1})),
+			size:   32,
+		},
+		"mapping(bytes4 => bool)": {
+			slot:   mustSlot(StateVar(2).MapFixedBytes([]byte{0xa9, 0x05, 0x9c, 0xbb})),
+			expect: crypto.Keccak256Hash(common.RightPadBytes([]byte{0xa9, 0x05, 0x9c, 0xbb}, 32), pad([]byte{2})),
+			size:   32,
+		},
+		"mapping(string => uint)": {
+			slot:   StateVar(2).MapBytes([]byte("hello")),
+			expect: crypto.Keccak256Hash([]byte("hello"), pad([]byte{2})),
+			size:   32,
+		},
+		"uint256[] at 0, element 7": {
+			slot:   mustSlot(StateVar(0).Element(7, 32)),
+			expect: common.BigToHash(new(big.Int).Add(array0.Big(), big.NewInt(7))),
+			size:   32,
+		},
+		"uint8[] at 1, element 33": {
+			slot:   mustSlot(StateVar(1).Element(33, 1)),
+			expect: common.BigToHash(new(big.Int).Add(array1.Big(), big.NewInt(1))),
+			offset: 1,
+			size:   1,
+		},
+		"address[] at 0, element 2": {
+			slot:   mustSlot(StateVar(0).Element(2, 20)),
+			expect: common.BigToHash(new(big.Int).Add(array0.Big(), big.NewInt(2))),
+			size:   20,
+		},
+		"struct of 3 slots [] at 0, element 2, member 1": {
+			slot:   mustSlot(StateVar(0).Element(2, 96)).Field(1),
+			expect: common.BigToHash(new(big.Int).Add(array0.Big(), big.NewInt(7))),
+			size:   32,
+		},
+		"uint128[4] at 6, element 3": {
+			slot:   mustSlot(StateVar(6).FixedElement(3, 16)),
+			expect: common.BigToHash(big.NewInt(7)),
+			offset: 16,
+			size:   16,
+		},
+		"packed uint64 after an address": {
+			slot:   mustSlot(StateVar(9).Packed(20, 8)),
+			expect: common.BigToHash(big.NewInt(9)),
+			offset: 20,
+			size:   8,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if tc.slot.Hash() != tc.expect {
+				t.Fatalf("Got slot %X, expected %X", tc.slot.Hash(), tc.expect)
+			}
+			if tc.slot.Offset() != tc.offset || tc.slot.Size() != tc.size {
+				t.Fatalf("Got offset %d size %d, expected %d %d", tc.slot.Offset(), tc.slot.Size(), tc.offset, tc.size)
+			}
+			if !bytes.Equal(tc.slot.TrieKey(), crypto.Keccak256(tc.expect[:])) {
+				t.Fatalf("Invalid trie key %X", tc.slot.TrieKey())
+			}
+		})
+	}
+
+	if _, err := StateVar(0).Packed(30, 4); err == nil {
+		t.Fatalf("Expected error packing past the end of the slot")
+	}
+	// offset+size wraps around to 1
+	if _, err := StateVar(0).Packed(^uint(0), 2); err == nil {
+		t.Fatalf("Expected error packing at an offset that overflows")
+	}
+	if _, err := StateVar(0).Element(1, 0); err == nil {
+		t.Fatalf("Expected error for elements of size 0")
+	}
+}
+
+func TestVerifyStorage(t *testing.T) {
+	token := common.HexToAddress("0x70c0")
+	holder := common.HexToAddress("0xdeadbeef")
+	balances := StateVar(3)
+	// slot 5 packs an address and a uint64: owner at offset 0, counter at offset 20
+	owner := common.HexToAddress("0x0123456789abcdef0123456789abcdef01234567")
+	packed := common.BytesToHash(append([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x2a}, owner[:]...))
+
+	diskdb, _, root := testState(t, func(statedb *state.StateDB) {
+		statedb.SetState(token, balances.MapAddress(holder).Hash(), common.BigToHash(big.NewInt(1000)))
+		statedb.SetState(token, StateVar(5).Hash(), packed)
+		statedb.SetState(token, StateVar(1).Hash(), common.BigToHash(big.NewInt(1)))
+	})
+	account, err := ComputeAccountProof(diskdb, root, token)
+	if err != nil {
+		t.Fatalf("ComputeAccountProof: %+v", err)
+	}
+	storageRoot := account.Account.StorageRoot
+
+	ownerSlot, _ := StateVar(5).Packed(0, 20)
+	counterSlot, _ := StateVar(5).Packed(20, 8)
+	cases := map[string]struct {
+		slot   Slot
+		expect []byte
+	}{
+		"balance":     {slot: balances.MapAddress(holder), expect: big.NewInt(1000).Bytes()},
+		"no balance":  {slot: balances.MapAddress(token), expect: nil},
+		"owner":       {slot: ownerSlot, expect: owner[:]},
+		"counter":     {slot: counterSlot, expect: []byte{0x2a}},
+		"small value": {slot: StateVar(1), expect: []byte{1}},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			proof, err := ComputeStorageProof(diskdb, storageRoot, tc.slot)
+			if err != nil {
+				t.Fatalf("ComputeStorageProof: %+v", err)
+			}
+			value, err := VerifyStorage(proof, storageRoot, tc.slot)
+			if err != nil {
+				t.Fatalf("VerifyStorage: %+v", err)
+			}
+			if !bytes.Equal(tc.expect, value.Big().Bytes()) {
+				t.Fatalf("Got %X, expected %X", value, tc.expect)
+			}
+			if _, err := VerifyStorage(proof, storageRoot, StateVar(42)); err == nil {
+				t.Fatalf("Expected error for proof of another slot")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package proof
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// StorageWord is the 32 byte word held in a storage slot, or a variable packed in one,
+// right aligned like the EVM loads it
+type StorageWord common.Hash
+
+// DecodeStorageValue parses the value of a slot in the storage trie, which is the rlp of
+// the word without leading zeros. A nil value (from an absence proof) is the zero word.
+// Anything but the canonical encoding is rejected, including an explicit zero, as zero
+// words are deleted from the trie rather than stored.
+func DecodeStorageValue(value []byte) (StorageWord, error) {
+	if value == nil {
+		return StorageWord{}, nil
+	}
+	// DecodeBytes rejects trailing data and non-minimal string headers
+	var trimmed []byte
+	if err := rlp.DecodeBytes(value, &trimmed); err != nil {
+		return StorageWord{}, fmt.Errorf("invalid storage value: %v", err)
+	}
+	switch {
+	case len(trimmed) == 0:
+		return StorageWord{}, fmt.Errorf("non-canonical storage value: zero is never stored")
+	case trimmed[0] == 0:
+		return StorageWord{}, fmt.Errorf("non-canonical storage value: leading zero")
+	case len(trimmed) > common.HashLength:
+		return StorageWord{}, fmt.Errorf("storage value has %d bytes", len(trimmed))
+	}
+	return StorageWord(common.BytesToHash(trimmed)), nil
+}
+
+// Hash returns the word as is
+func (w StorageWord) Hash() common.Hash {
+	return common.Hash(w)
+}
+
+// Big returns the word as an unsigned integer
+func (w StorageWord) Big() *big.Int {
+	return new(big.Int).SetBytes(w[:])
+}
+
+// Address returns the word as an address, making sure nothing is set above the low 20 bytes
+func (w StorageWord) Address() (common.Address, error) {
+	if !isZero(w[:common.HashLength-common.AddressLength]) {
+		return common.Address{}, fmt.Errorf("word %X is not an address", w[:])
+	}
+	return common.BytesToAddress(w[:]), nil
+}
+
+// Bool returns the word as a bool, making sure it is 0 or 1
+func (w StorageWord) Bool() (bool, error) {
+	if !isZero(w[:common.HashLength-1]) || w[common.HashLength-1] > 1 {
+		return false, fmt.Errorf("word %X is not a bool", w[:])
+	}
+	return w[common.HashLength-1] == 1, nil
+}
+
+// Field returns the size bytes at offset (counted from the low-order end, as Solidity packs
+// variables) as a word of their own, so they can be read with the other accessors
+func (w StorageWord) Field(offset, size uint) (StorageWord, error) {
+	if size == 0 || size > common.HashLength || offset > common.HashLength-size {
+		return StorageWord{}, fmt.Errorf("cannot read %d bytes at offset %d in a word", size, offset)
+	}
+	end := common.HashLength - offset
+	return StorageWord(common.BytesToHash(w[end-size : end])), nil
+}
+
+// At returns the variable slot points to in this word
+func (w StorageWord) At(slot Slot) (StorageWord, error) {
+	return w.Field(slot.Offset(), slot.Size())
+}
+
+func isZero(b []byte) bool {
+	for _, x := range b {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
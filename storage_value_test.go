@@ -0,0 +1,95 @@
+package proof
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestDecodeStorageValue(t *testing.T) {
+	cases := map[string]struct {
+		value  []byte
+		expect *big.Int
+		isErr  bool
+	}{
+		"absent":           {value: nil, expect: big.NewInt(0)},
+		"single byte":      {value: []byte{0x05}, expect: big.NewInt(5)},
+		"short string":     {value: []byte{0x82, 0x03, 0xe8}, expect: big.NewInt(1000)},
+		"full word":        {value: append([]byte{0xa0, 0xff}, make([]byte, 31)...), expect: new(big.Int).Lsh(big.NewInt(0xff), 248)},
+		"explicit zero":    {value: []byte{0x80}, isErr: true},
+		"zero byte":        {value: []byte{0x00}, isErr: true},
+		"leading zero":     {value: []byte{0x82, 0x00, 0x01}, isErr: true},
+		"single byte str":  {value: []byte{0x81, 0x05}, isErr: true},
+		"too long":         {value: append([]byte{0xa1, 0x01}, make([]byte, 32)...), isErr: true},
+		"trailing data":    {value: []byte{0x05, 0x06}, isErr: true},
+		"list":             {value: []byte{0xc1, 0x05}, isErr: true},
+		"truncated string": {value: []byte{0x83, 0x01, 0x02}, isErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			word, err := DecodeStorageValue(tc.value)
+			if tc.isErr {
+				if err == nil {
+					t.Fatalf("Expected error, got %X", word)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeStorageValue: %+v", err)
+			}
+			if word.Big().Cmp(tc.expect) != 0 {
+				t.Fatalf("Got %s, expected %s", word.Big(), tc.expect)
+			}
+		})
+	}
+}
+
+func TestStorageWordAccessors(t *testing.T) {
+	owner := common.HexToAddress("0x0123456789abcdef0123456789abcdef01234567")
+	// bool paused at offset 28, uint64 counter at offset 20, address owner at offset 0
+	var packed StorageWord
+	packed[3] = 1
+	packed[11] = 0x2a
+	copy(packed[12:], owner[:])
+
+	got, err := packed.Field(0, 20)
+	if err != nil {
+		t.Fatalf("Field: %+v", err)
+	}
+	addr, err := got.Address()
+	if err != nil || addr != owner {
+		t.Fatalf("Got owner %X (%v)", addr, err)
+	}
+	got, err = packed.Field(20, 8)
+	if err != nil || got.Big().Int64() != 0x2a {
+		t.Fatalf("Got counter %s (%v)", got.Big(), err)
+	}
+	got, err = packed.Field(28, 1)
+	if err != nil {
+		t.Fatalf("Field: %+v", err)
+	}
+	paused, err := got.Bool()
+	if err != nil || !paused {
+		t.Fatalf("Got paused %t (%v)", paused, err)
+	}
+
+	// the whole word is none of those
+	if _, err := packed.Address(); err == nil {
+		t.Fatalf("Expected error reading packed word as address")
+	}
+	if _, err := packed.Bool(); err == nil {
+		t.Fatalf("Expected error reading packed word as bool")
+	}
+	if _, err := packed.Field(25, 8); err == nil {
+		t.Fatalf("Expected error reading past the end of the word")
+	}
+	// offset+size wraps around to 1
+	if _, err := packed.Field(^uint(0), 2); err == nil {
+		t.Fatalf("Expected error reading at an offset that overflows")
+	}
+	if _, err := (StorageWord{31: 2}).Bool(); err == nil {
+		t.Fatalf("Expected error reading 2 as bool")
+	}
+}